@@ -1,31 +1,47 @@
 package backend
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
-// Graph is the interface that all drivers must implement
+// Graph is the interface that all drivers must implement.  Every method takes a context that drivers honor
+// between the calls they make to the backend so a caller can cancel or put a deadline on slow operations.
+//
+// The batch methods report the outcome for each node or edge in the returned results so a single bad item does
+// not hide what happened to the rest.  The error is only for when the batch as a whole failed, in which case the
+// results may be nil.
 type Graph interface {
 	// Gets
-	GetNodes(*Nodes) error
-	GetInEdges(*Edges) error
-	GetOutEdges(*Edges) error
+	GetNodes(context.Context, *Nodes) (NodeResults, error)
+	GetInEdges(context.Context, *Edges) (EdgeResults, error)
+	GetOutEdges(context.Context, *Edges) (EdgeResults, error)
 
 	// Creates
-	CreateNodes(*Nodes) error
-	CreateEdges(*Edges) error
+	CreateNodes(context.Context, *Nodes) (NodeResults, error)
+	CreateEdges(context.Context, *Edges) (EdgeResults, error)
 
 	// Alters
-	AlterNodes(*Nodes) error
-	//	AlterEdges(*Edges) error
-	//
-	//	DeleteNodes(*Nodes) error
-	//	DeleteEdges(*Edges) error
-	//
-	//	GetPath(*Nodes) (*Path, error)
-	//
+	AlterNodes(context.Context, *Nodes) (NodeResults, error)
+	AlterEdges(context.Context, *Edges) (EdgeResults, error)
+
+	// Deletes
+	DeleteNodes(context.Context, *Nodes) (NodeResults, error)
+	DeleteEdges(context.Context, *Edges) (EdgeResults, error)
+
+	// Paths
+	GetPath(ctx context.Context, root string, path string) (*Path, error)
+
 	//	GetConnection() (*Connection, error)
 	//	Ping() error
 }
 
+// Closer is implemented by drivers that hold on to connections, such as a pool, that should be released once the
+// graph is no longer used
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
 // DriverInitializer is a signature that all drivers must have to register it's backen with the FS
 type DriverInitializer func(*Config) (Graph, error)
 
@@ -39,19 +55,19 @@ func RegisterBackend(name string, i DriverInitializer) {
 // GetBackend returns a driver based on a config
 func GetBackend(cfg *Config) (Graph, error) {
 	// pull the driver out of the registered backends
-	factory, ok := registry[cfg.StringKey("name")]
+	name := cfg.StringKey("name")
+	factory, ok := registry[name]
 	if !ok {
-		return nil, fmt.Errorf(
-			fmt.Sprintf("A backend with the name \"%s\" has not been registered", cfg.StringKey("name")),
+		return nil, NewError(name, "GetBackend", ErrUnsupported,
+			fmt.Errorf("A backend with the name \"%s\" has not been registered", name),
 		)
 	}
 
-	// setup the driver with all the connections it will need to be useful.
+	// setup the driver with all the connections it will need to be useful.  The error is wrapped rather than
+	// flattened so the kind of error the driver returned can still be checked.
 	graph, err := factory(cfg)
 	if err != nil {
-		return nil, fmt.Errorf(
-			fmt.Sprintf("Failed to initialize with error message %s", err.Error()),
-		)
+		return nil, fmt.Errorf("Failed to initialize %s: %w", name, err)
 	}
 
 	return graph, err
@@ -0,0 +1,20 @@
+package backend
+
+// CapacityReporter is implemented by drivers whose backend bills by the capacity each request consumes
+type CapacityReporter interface {
+	// ConsumedCapacity returns the capacity consumed since the process started, one entry for every source id
+	// and operation that has sent a request
+	ConsumedCapacity() []CapacityUsage
+}
+
+// CapacityUsage is the capacity consumed by the requests of one operation for one source id.  The totals only
+// ever grow, so they can be exported as counters.
+type CapacityUsage struct {
+	SourceID string
+	// Op is the operation of the backend, e.g. "Query"
+	Op string
+	// Requests counts every request sent, retries included
+	Requests   int64
+	ReadUnits  float64
+	WriteUnits float64
+}
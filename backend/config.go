@@ -5,6 +5,12 @@ import "fmt"
 // An interface to allow different drivers to have their own unique config.
 type Config map[string]interface{}
 
+// HasKey reports whether the config has a value for key
+func (c Config) HasKey(key string) bool {
+	_, ok := c[key]
+	return ok
+}
+
 // helper function to extract a string from the config
 func (c Config) StringKey(key string) string {
 	if val, ok := c[key]; ok {
@@ -32,3 +38,16 @@ func (c Config) IntKey(key string) int {
 	}
 	panic(fmt.Errorf("No such key: %s", key))
 }
+
+// helper function to extract a bool from the config
+func (c Config) BoolKey(key string) bool {
+	if val, ok := c[key]; ok {
+		switch vv := val.(type) {
+		case bool:
+			return vv
+		default:
+			panic(fmt.Errorf("Invalid %s parameter type from config: %T", key, val))
+		}
+	}
+	panic(fmt.Errorf("No such key: %s", key))
+}
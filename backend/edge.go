@@ -1,8 +1,13 @@
 package backend
 
+// Edges maps a from nid to a to nid to the properties of the edge between them. Used both in the request and
+// the response
 type Edges map[string]map[string]*Properties
 
 func (e *Edges) GetEdgeByID(fid, tid string) *Properties {
+	if (*e)[fid] == nil {
+		(*e)[fid] = make(map[string]*Properties)
+	}
 	edge := (*e)[fid][tid]
 	if edge == nil {
 		edge = &Properties{}
@@ -0,0 +1,51 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The kinds of errors a driver can return.  Drivers map their native errors into one of these so callers can
+// check for them with errors.Is regardless of the backend in use.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	ErrConflict      = errors.New("conflict")
+	ErrUnsupported   = errors.New("unsupported")
+	ErrThrottled     = errors.New("throttled")
+	ErrUnavailable   = errors.New("unavailable")
+)
+
+// Error is an error from a backend operation.  Kind is one of the errors above, or nil if the error is none of
+// them, and Err is the underlying error from the driver which stays reachable through errors.As.
+type Error struct {
+	Driver string
+	Op     string
+	Kind   error
+	Err    error
+}
+
+// NewError returns an Error for the operation op of driver.  kind is nil if err is none of the known kinds.
+func NewError(driver, op string, kind, err error) *Error {
+	return &Error{Driver: driver, Op: op, Kind: kind, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Kind == nil {
+		return fmt.Sprintf("%s %s: %s", e.Driver, e.Op, e.Err.Error())
+	}
+	if e.Err == nil {
+		return fmt.Sprintf("%s %s: %s", e.Driver, e.Op, e.Kind.Error())
+	}
+	return fmt.Sprintf("%s %s: %s: %s", e.Driver, e.Op, e.Kind.Error(), e.Err.Error())
+}
+
+// Unwrap returns the underlying driver error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error is of the given kind
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
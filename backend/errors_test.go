@@ -0,0 +1,51 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type driverError struct{ code string }
+
+func (e *driverError) Error() string { return e.code }
+
+func Test_ErrorIs(t *testing.T) {
+	native := &driverError{"ThrottlingException"}
+	err := fmt.Errorf("get nodes: %w", NewError("ddb", "GetNodes", ErrThrottled, native))
+
+	// + test
+	if !errors.Is(err, ErrThrottled) {
+		t.Error("expected the error to be ErrThrottled")
+	}
+	var derr *driverError
+	if !errors.As(err, &derr) || derr != native {
+		t.Error("expected the native error to be reachable with errors.As")
+	}
+	var berr *Error
+	if !errors.As(err, &berr) || berr.Op != "GetNodes" {
+		t.Error("expected the backend error to be reachable with errors.As")
+	}
+
+	// - test
+	if errors.Is(err, ErrNotFound) {
+		t.Error("a throttled error should not be ErrNotFound")
+	}
+	if errors.Is(NewError("ddb", "GetNodes", nil, native), ErrThrottled) {
+		t.Error("an error without a kind should not match any kind")
+	}
+}
+
+func Test_PathErrorIsNotFound(t *testing.T) {
+	var err error = &PathError{Path: "/a", Component: "a", Parent: "root"}
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("expected a PathError to be ErrNotFound")
+	}
+}
+
+func Test_GetBackendUnregistered(t *testing.T) {
+	_, err := GetBackend(&Config{"name": "no-such-backend"})
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported got %v", err)
+	}
+}
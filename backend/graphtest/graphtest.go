@@ -0,0 +1,736 @@
+// Package graphtest is a battery of behavioral tests that every backend.Graph driver should pass.  A driver
+// validates itself by calling Run from its own tests with its initializer and a config to connect with.
+package graphtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// Run runs every test in the suite against the driver returned by newGraph.  Each test gets its own driver
+// with a fresh "sid" in a copy of cfg so the tests do not see each other's nodes and edges.
+func Run(t *testing.T, newGraph backend.DriverInitializer, cfg backend.Config) {
+	tests := []struct {
+		name string
+		test func(*testing.T, *suite)
+	}{
+		{"CreateAndGetNodes", testCreateAndGetNodes},
+		{"GetMissingNodes", testGetMissingNodes},
+		{"AlterNodes", testAlterNodes},
+		{"DeleteNodes", testDeleteNodes},
+		{"OutEdges", testOutEdges},
+		{"InEdges", testInEdges},
+		{"AlterEdges", testAlterEdges},
+		{"DeleteEdges", testDeleteEdges},
+		{"CreateExisting", testCreateExisting},
+		{"LargeBatches", testLargeBatches},
+		{"SourceIsolation", testSourceIsolation},
+		{"GetPath", testGetPath},
+		{"PropertyTypes", testPropertyTypes},
+		{"Transactions", testTransactions},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s := &suite{t: t, ctx: context.Background(), newGraph: newGraph, cfg: cfg}
+			s.g = s.graph(newSid(t))
+			tt.test(t, s)
+		})
+	}
+}
+
+// suite holds what a single test needs to talk to the driver
+type suite struct {
+	t        *testing.T
+	ctx      context.Context
+	newGraph backend.DriverInitializer
+	cfg      backend.Config
+	g        backend.Graph
+}
+
+// graph returns a new driver for the given sid
+func (s *suite) graph(sid string) backend.Graph {
+	cfg := make(backend.Config, len(s.cfg)+1)
+	for key, value := range s.cfg {
+		cfg[key] = value
+	}
+	cfg["sid"] = sid
+	g, err := s.newGraph(&cfg)
+	if err != nil {
+		s.t.Fatalf("new graph: %s", err.Error())
+	}
+	return g
+}
+
+func newSid(t *testing.T) string {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		t.Fatalf("new sid: %s", err.Error())
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// named returns edge properties with the given name
+func named(name string) *backend.Properties {
+	properties := &backend.Properties{}
+	properties.SetString("name", name)
+	return properties
+}
+
+// file returns node properties for a file with the given size
+func file(size int) *backend.Properties {
+	properties := &backend.Properties{}
+	properties.SetString("type", "file")
+	properties.SetInt("size", size)
+	return properties
+}
+
+// createNodes creates the nodes and fails the test if any of them could not be created
+func (s *suite) createNodes(nodes backend.Nodes) {
+	s.t.Helper()
+	results, err := s.g.CreateNodes(s.ctx, &nodes)
+	if err != nil {
+		s.t.Fatalf("create nodes: %s", err.Error())
+	}
+	if err := results.Err(); err != nil {
+		s.t.Fatalf("create nodes: %s", err.Error())
+	}
+}
+
+// createEdges creates the edges and fails the test if any of them could not be created
+func (s *suite) createEdges(edges backend.Edges) {
+	s.t.Helper()
+	results, err := s.g.CreateEdges(s.ctx, &edges)
+	if err != nil {
+		s.t.Fatalf("create edges: %s", err.Error())
+	}
+	if err := results.Err(); err != nil {
+		s.t.Fatalf("create edges: %s", err.Error())
+	}
+}
+
+// expectString fails the test if the property is not the expected string
+func expectString(t *testing.T, what string, properties *backend.Properties, key, expected string) {
+	t.Helper()
+	if properties == nil {
+		t.Errorf("%s: expected %s to be %q got no properties", what, key, expected)
+		return
+	}
+	value, err := properties.GetString(key)
+	if err != nil {
+		t.Errorf("%s: %s", what, err.Error())
+		return
+	}
+	if value != expected {
+		t.Errorf("%s: expected %s to be %q got %q", what, key, expected, value)
+	}
+}
+
+// expectNumber fails the test if the property is not the expected number
+func expectNumber(t *testing.T, what string, properties *backend.Properties, key string, expected int) {
+	t.Helper()
+	value, err := properties.GetInt(key)
+	if err != nil {
+		t.Errorf("%s: %s", what, err.Error())
+		return
+	}
+	if value != expected {
+		t.Errorf("%s: expected %s to be %d got %d", what, key, expected, value)
+	}
+}
+
+// expectProperties fails the test if any of the expected properties is missing or differs in type or value.
+// Properties are compared by their JSON encoding so times in different locations are still equal.
+func expectProperties(t *testing.T, what string, properties, expected *backend.Properties) {
+	t.Helper()
+	for key, property := range *expected {
+		got, ok := (*properties)[key]
+		if !ok {
+			t.Errorf("%s: expected %s got nothing", what, key)
+			continue
+		}
+		e, _ := json.Marshal(property)
+		g, err := json.Marshal(got)
+		if err != nil || string(e) != string(g) {
+			t.Errorf("%s: expected %s to be %s got %s", what, key, e, g)
+		}
+	}
+}
+
+// typed returns properties holding every property type, nesting the containers in each other
+func typed() *backend.Properties {
+	inner := &backend.Properties{}
+	inner.SetFloat("ratio", 0.5)
+	inner.SetTime("at", time.Date(2017, 6, 1, 12, 30, 0, 123456789, time.UTC))
+	inner.SetList("tags", []*backend.Property{{Type: backend.StringProperty, Value: "a"}, {Type: backend.StringProperty, Value: "b"}})
+
+	properties := &backend.Properties{}
+	properties.SetString("string", "test")
+	properties.SetInt("number", 42)
+	properties.SetNumber("precise", backend.MustParseNumber("-12345678901234567890.123456789012345678"))
+	properties.SetBinary("binary", []byte{0, 1, 2, 255})
+	properties.SetBool("bool", true)
+	properties.SetFloat("float", 1.25)
+	properties.SetFloat("whole", 3)
+	properties.SetTime("time", time.Date(2017, 6, 1, 12, 30, 0, 0, time.UTC))
+	properties.SetList("floats", []*backend.Property{{Type: backend.FloatProperty, Value: 1.5}, {Type: backend.FloatProperty, Value: 2.0}})
+	properties.SetList("mixed", []*backend.Property{{Type: backend.NumberProperty, Value: backend.NumberFromInt64(1)}, {Type: backend.StringProperty, Value: "1"}, {Type: backend.MapProperty, Value: inner}})
+	properties.SetList("empty", []*backend.Property{})
+	properties.SetMap("map", inner)
+	return properties
+}
+
+// expectResult fails the test if the result is not of the expected kind, or not nil when kind is nil
+func expectResult(t *testing.T, what string, result, kind error) {
+	t.Helper()
+	if kind == nil && result != nil {
+		t.Errorf("%s: expected success got %s", what, result.Error())
+	}
+	if kind != nil && !errors.Is(result, kind) {
+		t.Errorf("%s: expected %s got %v", what, kind.Error(), result)
+	}
+}
+
+func testCreateAndGetNodes(t *testing.T, s *suite) {
+	s.createNodes(backend.Nodes{"1": file(10), "2": file(20)})
+
+	nodes := &backend.Nodes{"1": &backend.Properties{}, "2": &backend.Properties{}}
+	results, err := s.g.GetNodes(s.ctx, nodes)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for nid, size := range map[string]int{"1": 10, "2": 20} {
+		expectResult(t, "get "+nid, results[nid], nil)
+		expectString(t, "get "+nid, (*nodes)[nid], "type", "file")
+		expectNumber(t, "get "+nid, (*nodes)[nid], "size", size)
+	}
+}
+
+func testGetMissingNodes(t *testing.T, s *suite) {
+	s.createNodes(backend.Nodes{"1": file(10)})
+
+	nodes := &backend.Nodes{"1": &backend.Properties{}, "4": &backend.Properties{}}
+	results, err := s.g.GetNodes(s.ctx, nodes)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "get 1", results["1"], nil)
+	expectString(t, "get 1", (*nodes)["1"], "type", "file")
+	expectResult(t, "get 4", results["4"], backend.ErrNotFound)
+}
+
+func testAlterNodes(t *testing.T, s *suite) {
+	s.createNodes(backend.Nodes{"1": file(10)})
+
+	alter := &backend.Properties{}
+	alter.SetString("type", "folder")
+	results, err := s.g.AlterNodes(s.ctx, &backend.Nodes{"1": alter, "4": file(40)})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "alter 1", results["1"], nil)
+	expectResult(t, "alter 4", results["4"], backend.ErrNotFound)
+
+	nodes := &backend.Nodes{"1": &backend.Properties{}, "4": &backend.Properties{}}
+	results, err = s.g.GetNodes(s.ctx, nodes)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectString(t, "altered 1", (*nodes)["1"], "type", "folder")
+	expectNumber(t, "altered 1", (*nodes)["1"], "size", 10)
+	expectResult(t, "altering a missing node should not create it", results["4"], backend.ErrNotFound)
+
+	// a nil property removes it
+	results, err = s.g.AlterNodes(s.ctx, &backend.Nodes{"1": &backend.Properties{"size": nil}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "remove size of 1", results["1"], nil)
+
+	nodes = &backend.Nodes{"1": &backend.Properties{}}
+	if _, err := s.g.GetNodes(s.ctx, nodes); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, ok := (*(*nodes)["1"])["size"]; ok {
+		t.Error("expected size to be removed from 1")
+	}
+	expectString(t, "removed size of 1", (*nodes)["1"], "type", "folder")
+}
+
+func testDeleteNodes(t *testing.T, s *suite) {
+	s.createNodes(backend.Nodes{"1": file(10), "2": file(20)})
+
+	results, err := s.g.DeleteNodes(s.ctx, &backend.Nodes{"1": &backend.Properties{}, "4": &backend.Properties{}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "delete 1", results["1"], nil)
+	expectResult(t, "deleting a missing node", results["4"], nil)
+
+	results, err = s.g.GetNodes(s.ctx, &backend.Nodes{"1": &backend.Properties{}, "2": &backend.Properties{}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "deleted 1", results["1"], backend.ErrNotFound)
+	expectResult(t, "kept 2", results["2"], nil)
+
+	// + test
+	// a node that still has edges is deleted along with them
+	s.createNodes(backend.Nodes{"root": &backend.Properties{}, "3": file(30)})
+	s.createEdges(backend.Edges{"root": {"3": named("a")}, "3": {"2": named("b")}})
+	results, err = s.g.DeleteNodes(s.ctx, &backend.Nodes{"3": &backend.Properties{}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "delete 3 with edges", results["3"], nil)
+
+	out := &backend.Edges{"root": {}, "3": {}}
+	if _, err := s.g.GetOutEdges(s.ctx, out); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len((*out)["root"]) != 0 || len((*out)["3"]) != 0 {
+		t.Errorf("expected the edges of 3 to be deleted got %d out of root and %d out of 3", len((*out)["root"]), len((*out)["3"]))
+	}
+	in := &backend.Edges{"2": {}}
+	if _, err := s.g.GetInEdges(s.ctx, in); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len((*in)["2"]) != 0 {
+		t.Errorf("expected the edge from 3 into 2 to be deleted got %d edges into 2", len((*in)["2"]))
+	}
+
+	// + test
+	// the name of a deleted edge is free again
+	edgeResults, err := s.g.CreateEdges(s.ctx, &backend.Edges{"root": {"2": named("a")}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "reuse the name of a deleted edge", edgeResults["root"]["2"], nil)
+}
+
+func testOutEdges(t *testing.T, s *suite) {
+	s.createNodes(backend.Nodes{"root": &backend.Properties{}, "1": file(10), "2": file(20)})
+	s.createEdges(backend.Edges{"root": {"1": named("a"), "2": named("b")}})
+
+	// every edge going out of root
+	edges := &backend.Edges{"root": {}}
+	if _, err := s.g.GetOutEdges(s.ctx, edges); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len((*edges)["root"]) != 2 {
+		t.Fatalf("expected 2 edges out of root got %d", len((*edges)["root"]))
+	}
+	expectString(t, "root -> 1", (*edges)["root"]["1"], "name", "a")
+	expectString(t, "root -> 2", (*edges)["root"]["2"], "name", "b")
+
+	// only the edges asked for
+	edges = &backend.Edges{"root": {"2": &backend.Properties{}, "3": &backend.Properties{}}}
+	results, err := s.g.GetOutEdges(s.ctx, edges)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "root -> 2", results["root"]["2"], nil)
+	expectString(t, "root -> 2", (*edges)["root"]["2"], "name", "b")
+	expectResult(t, "root -> 3", results["root"]["3"], backend.ErrNotFound)
+	if _, ok := (*edges)["root"]["1"]; ok {
+		t.Error("expected only the edges asked for")
+	}
+}
+
+func testInEdges(t *testing.T, s *suite) {
+	s.createNodes(backend.Nodes{"1": &backend.Properties{}, "2": &backend.Properties{}, "3": file(30)})
+	s.createEdges(backend.Edges{"1": {"3": named("a")}, "2": {"3": named("b")}})
+
+	edges := &backend.Edges{"3": {}}
+	if _, err := s.g.GetInEdges(s.ctx, edges); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len((*edges)["3"]) != 2 {
+		t.Fatalf("expected 2 edges into 3 got %d", len((*edges)["3"]))
+	}
+	expectString(t, "1 -> 3", (*edges)["3"]["1"], "name", "a")
+	expectString(t, "2 -> 3", (*edges)["3"]["2"], "name", "b")
+
+	// only the edges asked for
+	edges = &backend.Edges{"3": {"2": &backend.Properties{}, "4": &backend.Properties{}}}
+	results, err := s.g.GetInEdges(s.ctx, edges)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "2 -> 3", results["3"]["2"], nil)
+	expectString(t, "2 -> 3", (*edges)["3"]["2"], "name", "b")
+	expectResult(t, "4 -> 3", results["3"]["4"], backend.ErrNotFound)
+	if _, ok := (*edges)["3"]["1"]; ok {
+		t.Error("expected only the edges asked for")
+	}
+}
+
+func testAlterEdges(t *testing.T, s *suite) {
+	s.createNodes(backend.Nodes{"root": &backend.Properties{}, "1": file(10)})
+	s.createEdges(backend.Edges{"root": {"1": named("a")}})
+
+	results, err := s.g.AlterEdges(s.ctx, &backend.Edges{"root": {"1": named("renamed"), "2": named("b")}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "alter root -> 1", results["root"]["1"], nil)
+	expectResult(t, "alter root -> 2", results["root"]["2"], backend.ErrNotFound)
+
+	edges := &backend.Edges{"root": {}}
+	if _, err := s.g.GetOutEdges(s.ctx, edges); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len((*edges)["root"]) != 1 {
+		t.Errorf("altering a missing edge should not create it, got %d edges", len((*edges)["root"]))
+	}
+	expectString(t, "altered root -> 1", (*edges)["root"]["1"], "name", "renamed")
+
+	// an alter with nothing to set still reports a missing edge
+	results, err = s.g.AlterEdges(s.ctx, &backend.Edges{"root": {"1": &backend.Properties{}, "2": &backend.Properties{}}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "empty alter root -> 1", results["root"]["1"], nil)
+	expectResult(t, "empty alter root -> 2", results["root"]["2"], backend.ErrNotFound)
+
+	// - test
+	// a rename to a name already taken under the same parent is reported and left unchanged
+	s.createNodes(backend.Nodes{"2": file(20)})
+	s.createEdges(backend.Edges{"root": {"2": named("b")}})
+	results, err = s.g.AlterEdges(s.ctx, &backend.Edges{"root": {"2": named("renamed")}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "rename root -> 2 to a taken name", results["root"]["2"], backend.ErrAlreadyExists)
+
+	edges = &backend.Edges{"root": {}}
+	if _, err := s.g.GetOutEdges(s.ctx, edges); err != nil {
+		t.Fatal(err.Error())
+	}
+	expectString(t, "unchanged root -> 1", (*edges)["root"]["1"], "name", "renamed")
+	expectString(t, "unchanged root -> 2", (*edges)["root"]["2"], "name", "b")
+
+	// + test
+	// an edge keeps its own name
+	results, err = s.g.AlterEdges(s.ctx, &backend.Edges{"root": {"1": named("renamed")}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "rename root -> 1 to its own name", results["root"]["1"], nil)
+}
+
+func testDeleteEdges(t *testing.T, s *suite) {
+	s.createNodes(backend.Nodes{"root": &backend.Properties{}, "1": file(10), "2": file(20)})
+	s.createEdges(backend.Edges{"root": {"1": named("a"), "2": named("b")}})
+
+	results, err := s.g.DeleteEdges(s.ctx, &backend.Edges{"root": {"1": &backend.Properties{}}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "delete root -> 1", results["root"]["1"], nil)
+
+	edges := &backend.Edges{"root": {}}
+	if _, err := s.g.GetOutEdges(s.ctx, edges); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, ok := (*edges)["root"]["1"]; ok || len((*edges)["root"]) != 1 {
+		t.Errorf("expected only root -> 2 to be left got %v", (*edges)["root"])
+	}
+}
+
+func testCreateExisting(t *testing.T, s *suite) {
+	s.createNodes(backend.Nodes{"root": &backend.Properties{}, "1": file(10), "2": file(20)})
+	s.createEdges(backend.Edges{"root": {"1": named("a")}})
+
+	// - test
+	// an existing node is reported and left unchanged
+	nodeResults, err := s.g.CreateNodes(s.ctx, &backend.Nodes{"1": file(30), "3": file(40)})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "create existing 1", nodeResults["1"], backend.ErrAlreadyExists)
+	expectResult(t, "create 3", nodeResults["3"], nil)
+
+	nodes := &backend.Nodes{"1": &backend.Properties{}}
+	if _, err := s.g.GetNodes(s.ctx, nodes); err != nil {
+		t.Fatal(err.Error())
+	}
+	expectNumber(t, "unchanged 1", (*nodes)["1"], "size", 10)
+
+	// an existing edge and a name already taken under the same parent are both reported
+	create := &backend.Edges{"root": {"1": named("b"), "2": named("a")}}
+	results, err := s.g.CreateEdges(s.ctx, create)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "create existing root -> 1", results["root"]["1"], backend.ErrAlreadyExists)
+	expectResult(t, "create duplicate name root -> 2", results["root"]["2"], backend.ErrAlreadyExists)
+	expectString(t, "refused root -> 2", (*create)["root"]["2"], "name", "a")
+
+	edges := &backend.Edges{"root": {}}
+	if _, err := s.g.GetOutEdges(s.ctx, edges); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len((*edges)["root"]) != 1 {
+		t.Errorf("expected only the original edge got %d edges", len((*edges)["root"]))
+	}
+	expectString(t, "unchanged root -> 1", (*edges)["root"]["1"], "name", "a")
+
+	// + test
+	// the same name is fine under another parent
+	results, err = s.g.CreateEdges(s.ctx, &backend.Edges{"3": {"2": named("a")}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "create 3 -> 2", results["3"]["2"], nil)
+
+	// - test
+	// of two edges given the same name in one batch only one is created
+	results, err = s.g.CreateEdges(s.ctx, &backend.Edges{"3": {"1": named("c"), "root": named("c")}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	created, refused := 0, 0
+	for _, tid := range []string{"1", "root"} {
+		switch err := results["3"][tid]; {
+		case err == nil:
+			created++
+		case errors.Is(err, backend.ErrAlreadyExists):
+			refused++
+		default:
+			t.Errorf("create 3 -> %s: unexpected error %s", tid, err.Error())
+		}
+	}
+	if created != 1 || refused != 1 {
+		t.Errorf("expected one edge created and one refused got %d created and %d refused", created, refused)
+	}
+
+	edges = &backend.Edges{"3": {}}
+	if _, err := s.g.GetOutEdges(s.ctx, edges); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len((*edges)["3"]) != 2 {
+		t.Errorf("expected 3 -> 2 and one of the batch got %d edges out of 3", len((*edges)["3"]))
+	}
+}
+
+func testLargeBatches(t *testing.T, s *suite) {
+	const n = 150
+
+	nodes := make(backend.Nodes, n)
+	children := make(map[string]*backend.Properties, n)
+	for i := 0; i < n; i++ {
+		nid := fmt.Sprintf("%d", i+1)
+		nodes[nid] = file(i)
+		children[nid] = named("file-" + nid)
+	}
+	s.createNodes(backend.Nodes{"root": &backend.Properties{}})
+	s.createNodes(nodes)
+	s.createEdges(backend.Edges{"root": children})
+
+	get := make(backend.Nodes, n)
+	for nid := range nodes {
+		get[nid] = &backend.Properties{}
+	}
+	results, err := s.g.GetNodes(s.ctx, &get)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := results.Err(); err != nil {
+		t.Fatal(err.Error())
+	}
+	for i := 0; i < n; i++ {
+		nid := fmt.Sprintf("%d", i+1)
+		expectNumber(t, "get "+nid, get[nid], "size", i)
+	}
+
+	edges := &backend.Edges{"root": {}}
+	if _, err := s.g.GetOutEdges(s.ctx, edges); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len((*edges)["root"]) != n {
+		t.Errorf("expected %d edges out of root got %d", n, len((*edges)["root"]))
+	}
+
+	unlink := make(map[string]*backend.Properties, n)
+	for nid := range nodes {
+		unlink[nid] = &backend.Properties{}
+	}
+	edgeResults, err := s.g.DeleteEdges(s.ctx, &backend.Edges{"root": unlink})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := edgeResults.Err(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	remove := make(backend.Nodes, n+1)
+	for nid := range nodes {
+		remove[nid] = &backend.Properties{}
+	}
+	remove["root"] = &backend.Properties{}
+	results, err = s.g.DeleteNodes(s.ctx, &remove)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := results.Err(); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func testSourceIsolation(t *testing.T, s *suite) {
+	other := s.graph(newSid(t))
+
+	s.createNodes(backend.Nodes{"1": file(10), "2": file(20)})
+	s.createEdges(backend.Edges{"1": {"2": named("a")}})
+
+	results, err := other.GetNodes(s.ctx, &backend.Nodes{"1": &backend.Properties{}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "node from another sid", results["1"], backend.ErrNotFound)
+
+	edges := &backend.Edges{"1": {}}
+	if _, err := other.GetOutEdges(s.ctx, edges); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len((*edges)["1"]) != 0 {
+		t.Errorf("expected no edges from another sid got %d", len((*edges)["1"]))
+	}
+}
+
+func testGetPath(t *testing.T, s *suite) {
+	s.createNodes(backend.Nodes{"root": &backend.Properties{}, "1": &backend.Properties{}, "2": file(20)})
+	linked := named("b.txt")
+	linked.SetFloat("weight", 1.5)
+	s.createEdges(backend.Edges{"root": {"1": named("a")}, "1": {"2": linked}})
+
+	p, err := s.g.GetPath(s.ctx, "root", "/a/b.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(p.Nodes) != 3 || len(p.Edges) != 2 {
+		t.Fatalf("expected 3 nodes and 2 edges got %d and %d", len(p.Nodes), len(p.Edges))
+	}
+	if p.Last().ID != "2" {
+		t.Errorf("expected /a/b.txt to resolve to 2 got %s", p.Last().ID)
+	}
+	expectNumber(t, "/a/b.txt", p.Last().Properties, "size", 20)
+	expectString(t, "edge to b.txt", p.Edges[1].Properties, "name", "b.txt")
+	if weight, err := p.Edges[1].Properties.GetFloat("weight"); err != nil || weight != 1.5 {
+		t.Errorf("edge to b.txt: expected the weight to come along with the edge got %v %v", weight, err)
+	}
+
+	_, err = s.g.GetPath(s.ctx, "root", "/a/c.txt")
+	var perr *backend.PathError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected a PathError got %v", err)
+	}
+	if perr.Component != "c.txt" || perr.Parent != "1" {
+		t.Errorf("expected c.txt to be missing under 1 got %s under %s", perr.Component, perr.Parent)
+	}
+}
+
+func testPropertyTypes(t *testing.T, s *suite) {
+	s.createNodes(backend.Nodes{"1": typed(), "2": named("2")})
+	s.createEdges(backend.Edges{"1": {"2": typed()}})
+
+	nodes := &backend.Nodes{"1": &backend.Properties{}}
+	if _, err := s.g.GetNodes(s.ctx, nodes); err != nil {
+		t.Fatal(err.Error())
+	}
+	expectProperties(t, "get 1", (*nodes)["1"], typed())
+
+	edges := &backend.Edges{"1": {"2": &backend.Properties{}}}
+	if _, err := s.g.GetOutEdges(s.ctx, edges); err != nil {
+		t.Fatal(err.Error())
+	}
+	expectProperties(t, "get 1 -> 2", (*edges)["1"]["2"], typed())
+
+	// changing the type of a property should not leave anything of the old type behind
+	alter := &backend.Properties{}
+	alter.SetString("float", "no longer a float")
+	alter.SetTime("string", time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC))
+	alter.SetInt("floats", 7)
+	results, err := s.g.AlterNodes(s.ctx, &backend.Nodes{"1": alter})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "alter 1", results["1"], nil)
+
+	nodes = &backend.Nodes{"1": &backend.Properties{}}
+	if _, err := s.g.GetNodes(s.ctx, nodes); err != nil {
+		t.Fatal(err.Error())
+	}
+	expectProperties(t, "altered 1", (*nodes)["1"], alter)
+}
+
+func testTransactions(t *testing.T, s *suite) {
+	if _, ok := s.g.(backend.Transactor); !ok {
+		err := backend.WithTx(s.ctx, s.g, func(backend.Graph) error {
+			t.Error("fn should not be called without transactions")
+			return nil
+		})
+		expectResult(t, "transaction", err, backend.ErrUnsupported)
+		return
+	}
+
+	s.createNodes(backend.Nodes{"root": &backend.Properties{}, "1": file(10), "2": &backend.Properties{}})
+	s.createEdges(backend.Edges{"root": {"1": named("a")}})
+
+	// + test
+	// moving 1 from root to 2 commits both writes
+	err := backend.WithTx(s.ctx, s.g, func(g backend.Graph) error {
+		if _, err := g.CreateEdges(s.ctx, &backend.Edges{"2": {"1": named("a")}}); err != nil {
+			return err
+		}
+		_, err := g.DeleteEdges(s.ctx, &backend.Edges{"root": {"1": &backend.Properties{}}})
+		return err
+	})
+	expectResult(t, "commit", err, nil)
+
+	edges := &backend.Edges{"root": {"1": &backend.Properties{}}, "2": {"1": &backend.Properties{}}}
+	results, err := s.g.GetOutEdges(s.ctx, edges)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "committed delete", results["root"]["1"], backend.ErrNotFound)
+	expectResult(t, "committed create", results["2"]["1"], nil)
+
+	// - test
+	// an error from fn rolls back the writes made before it
+	failed := errors.New("failed")
+	err = backend.WithTx(s.ctx, s.g, func(g backend.Graph) error {
+		if _, err := g.DeleteEdges(s.ctx, &backend.Edges{"2": {"1": &backend.Properties{}}}); err != nil {
+			return err
+		}
+		if _, err := g.AlterNodes(s.ctx, &backend.Nodes{"1": file(20)}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("rollback: expected the error from fn got %v", err)
+	}
+
+	edges = &backend.Edges{"2": {"1": &backend.Properties{}}}
+	results, err = s.g.GetOutEdges(s.ctx, edges)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectResult(t, "rolled back delete", results["2"]["1"], nil)
+
+	nodes := &backend.Nodes{"1": &backend.Properties{}}
+	if _, err := s.g.GetNodes(s.ctx, nodes); err != nil {
+		t.Fatal(err.Error())
+	}
+	expectNumber(t, "rolled back alter", (*nodes)["1"], "size", 10)
+}
@@ -0,0 +1,131 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// names of the property types as they appear in JSON
+var propertyTypeNames = map[PropertyType]string{
+	StringProperty: "string",
+	NumberProperty: "number",
+	BinaryProperty: "binary",
+	BoolProperty:   "bool",
+	FloatProperty:  "float",
+	TimeProperty:   "time",
+	ListProperty:   "list",
+	MapProperty:    "map",
+}
+
+func (t PropertyType) String() string {
+	if name, ok := propertyTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("PropertyType(%d)", int(t))
+}
+
+// MarshalJSON encodes the type by its name
+func (t PropertyType) MarshalJSON() ([]byte, error) {
+	name, ok := propertyTypeNames[t]
+	if !ok {
+		return nil, fmt.Errorf("Invalid property type: %d", int(t))
+	}
+	return json.Marshal(name)
+}
+
+// UnmarshalJSON decodes a type from its name
+func (t *PropertyType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for pt, n := range propertyTypeNames {
+		if n == name {
+			*t = pt
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid property type: %s", name)
+}
+
+// jsonProperty is how a Property looks in JSON, e.g. {"type": "string", "value": "a.txt"}
+type jsonProperty struct {
+	Type  PropertyType    `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// MarshalJSON encodes the property with its type so it can be decoded back into the same value.  Binary values
+// are base64 encoded, numbers are kept as strings so no precision is lost and times are RFC 3339 strings with
+// nanoseconds.  Lists and maps hold encoded properties.
+func (p Property) MarshalJSON() ([]byte, error) {
+	value, err := json.Marshal(p.Value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonProperty{Type: p.Type, Value: value})
+}
+
+// UnmarshalJSON decodes a property written by MarshalJSON.  Numbers may also be given as JSON numbers.
+func (p *Property) UnmarshalJSON(data []byte) error {
+	var raw jsonProperty
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	switch raw.Type {
+	case StringProperty:
+		var s string
+		if err := json.Unmarshal(raw.Value, &s); err != nil {
+			return fmt.Errorf("Invalid string property value: %w", err)
+		}
+		p.Value = s
+	case NumberProperty:
+		var n Number
+		if err := json.Unmarshal(raw.Value, &n); err != nil {
+			return fmt.Errorf("Invalid number property value: %w", err)
+		}
+		p.Value = n
+	case BinaryProperty:
+		var b []byte
+		if err := json.Unmarshal(raw.Value, &b); err != nil {
+			return fmt.Errorf("Invalid binary property value: %w", err)
+		}
+		p.Value = b
+	case BoolProperty:
+		var b bool
+		if err := json.Unmarshal(raw.Value, &b); err != nil {
+			return fmt.Errorf("Invalid bool property value: %w", err)
+		}
+		p.Value = b
+	case FloatProperty:
+		var f float64
+		if err := json.Unmarshal(raw.Value, &f); err != nil {
+			return fmt.Errorf("Invalid float property value: %w", err)
+		}
+		p.Value = f
+	case TimeProperty:
+		var t time.Time
+		if err := json.Unmarshal(raw.Value, &t); err != nil {
+			return fmt.Errorf("Invalid time property value: %w", err)
+		}
+		p.Value = t
+	case ListProperty:
+		var l []*Property
+		if err := json.Unmarshal(raw.Value, &l); err != nil {
+			return fmt.Errorf("Invalid list property value: %w", err)
+		}
+		if l == nil {
+			l = []*Property{}
+		}
+		p.Value = l
+	case MapProperty:
+		m := &Properties{}
+		if err := json.Unmarshal(raw.Value, m); err != nil {
+			return fmt.Errorf("Invalid map property value: %w", err)
+		}
+		p.Value = m
+	}
+	p.Type = raw.Type
+	return nil
+}
@@ -0,0 +1,57 @@
+package backend
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_PropertiesJSON(t *testing.T) {
+	properties := &Properties{}
+	properties.SetString("name", "a.txt")
+	properties.SetInt("size", 12)
+	properties.SetBinary("hash", []byte{0, 1, 2})
+	properties.SetBool("hidden", true)
+	properties.SetFloat("ratio", 0.25)
+	properties.SetTime("modified", time.Date(2017, 3, 4, 5, 6, 7, 8, time.UTC))
+	inner := &Properties{}
+	inner.SetString("owner", "me")
+	properties.SetMap("meta", inner)
+	properties.SetList("tags", []*Property{{StringProperty, "a"}, {NumberProperty, MustParseNumber("1")}, {MapProperty, inner}})
+	properties.SetList("empty", []*Property{})
+
+	// + test
+	data, err := json.Marshal(properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &Properties{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(properties, decoded) {
+		t.Errorf("expected %s got %v", data, decoded)
+	}
+
+	decoded = &Properties{}
+	if err := json.Unmarshal([]byte(`{"size": {"type": "number", "value": 12}}`), decoded); err != nil {
+		t.Fatal(err)
+	}
+	if (*decoded)["size"].Value != MustParseNumber("12") {
+		t.Errorf("expected a JSON number to decode to 12 got %v", (*decoded)["size"].Value)
+	}
+
+	// - test
+	for _, data := range []string{
+		`{"a": {"type": "bogus", "value": "x"}}`,
+		`{"a": {"type": "string", "value": 1}}`,
+		`{"a": {"type": "number", "value": "x"}}`,
+		`{"a": {"type": "time", "value": "yesterday"}}`,
+		`{"a": {"type": "list", "value": [{"type": "bool", "value": "x"}]}}`,
+	} {
+		if err := json.Unmarshal([]byte(data), &Properties{}); err == nil {
+			t.Errorf("%s: expected an error", data)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The limits of a Number, which are the same as those of the DynamoDB N type
+const (
+	// MaxNumberDigits is the most significant digits a number can have
+	MaxNumberDigits = 38
+	// a number's magnitude must be below 1E+126 and at least 1E-130
+	maxNumberMagnitude = 126
+	minNumberMagnitude = -129
+)
+
+// ErrNumberRange is returned when a number does not fit within the limits of a Number, or within the type it is
+// being converted to
+var ErrNumberRange = errors.New("number out of range")
+
+// Number is a decimal number of up to 38 significant digits, matching the DynamoDB N type so numbers go through
+// any driver without losing precision.  The zero value is 0.  Numbers are kept in their canonical form so two
+// Numbers are equal with == exactly when they have the same value.
+type Number struct {
+	// canonical plain decimal representation, empty for zero
+	s string
+}
+
+// ParseNumber parses a decimal number such as "42", "-0.5" or "1.5E+10"
+func ParseNumber(s string) (Number, error) {
+
+	str := s
+	negative := false
+	switch {
+	case strings.HasPrefix(str, "-"):
+		negative, str = true, str[1:]
+	case strings.HasPrefix(str, "+"):
+		str = str[1:]
+	}
+
+	mantissa, exponent := str, 0
+	if i := strings.IndexAny(str, "eE"); i >= 0 {
+		e, err := strconv.Atoi(strings.TrimPrefix(str[i+1:], "+"))
+		if err != nil {
+			return Number{}, fmt.Errorf("Invalid number %q", s)
+		}
+		mantissa, exponent = str[:i], e
+	}
+
+	whole, fraction := mantissa, ""
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		whole, fraction = mantissa[:i], mantissa[i+1:]
+	}
+	digits := whole + fraction
+	if digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return Number{}, fmt.Errorf("Invalid number %q", s)
+	}
+	exponent -= len(fraction)
+
+	// normalize to the significant digits times a power of ten
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return Number{}, nil
+	}
+	trimmed := strings.TrimRight(digits, "0")
+	exponent += len(digits) - len(trimmed)
+	digits = trimmed
+
+	if len(digits) > MaxNumberDigits {
+		return Number{}, fmt.Errorf("%s has more than %d significant digits: %w", s, MaxNumberDigits, ErrNumberRange)
+	}
+	if magnitude := exponent + len(digits); magnitude > maxNumberMagnitude || magnitude < minNumberMagnitude {
+		return Number{}, fmt.Errorf("%s: %w", s, ErrNumberRange)
+	}
+
+	var b strings.Builder
+	if negative {
+		b.WriteByte('-')
+	}
+	switch {
+	case exponent >= 0:
+		b.WriteString(digits)
+		b.WriteString(strings.Repeat("0", exponent))
+	case -exponent < len(digits):
+		b.WriteString(digits[:len(digits)+exponent])
+		b.WriteByte('.')
+		b.WriteString(digits[len(digits)+exponent:])
+	default:
+		b.WriteString("0.")
+		b.WriteString(strings.Repeat("0", -exponent-len(digits)))
+		b.WriteString(digits)
+	}
+	return Number{s: b.String()}, nil
+}
+
+// MustParseNumber is like ParseNumber but panics if s is not a valid number.  It is meant for constants.
+func MustParseNumber(s string) Number {
+	n, err := ParseNumber(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// NumberFromInt64 returns the number with the value of i
+func NumberFromInt64(i int64) Number {
+	return MustParseNumber(strconv.FormatInt(i, 10))
+}
+
+// NumberFromUint64 returns the number with the value of u
+func NumberFromUint64(u uint64) Number {
+	return MustParseNumber(strconv.FormatUint(u, 10))
+}
+
+// NumberFromFloat64 returns the number with the shortest decimal representation that converts back to f.  NaN,
+// infinities and values outside the limits of a Number are ErrNumberRange.
+func NumberFromFloat64(f float64) (Number, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Number{}, fmt.Errorf("%v: %w", f, ErrNumberRange)
+	}
+	return ParseNumber(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// String returns the number in plain decimal notation without an exponent
+func (n Number) String() string {
+	if n.s == "" {
+		return "0"
+	}
+	return n.s
+}
+
+// IsInteger reports whether the number has no fractional part
+func (n Number) IsInteger() bool {
+	return !strings.Contains(n.s, ".")
+}
+
+// Int64 returns the number as an int64 or ErrNumberRange if it has a fractional part or does not fit
+func (n Number) Int64() (int64, error) {
+	if !n.IsInteger() {
+		return 0, fmt.Errorf("%s is not an integer: %w", n, ErrNumberRange)
+	}
+	i, err := strconv.ParseInt(n.String(), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s does not fit in an int64: %w", n, ErrNumberRange)
+	}
+	return i, nil
+}
+
+// Uint64 returns the number as a uint64 or ErrNumberRange if it has a fractional part or does not fit
+func (n Number) Uint64() (uint64, error) {
+	if !n.IsInteger() {
+		return 0, fmt.Errorf("%s is not an integer: %w", n, ErrNumberRange)
+	}
+	u, err := strconv.ParseUint(n.String(), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s does not fit in a uint64: %w", n, ErrNumberRange)
+	}
+	return u, nil
+}
+
+// Float64 returns the float64 nearest to the number.  Every Number is within the range of a float64 but most
+// lose some precision.
+func (n Number) Float64() (float64, error) {
+	f, err := strconv.ParseFloat(n.String(), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s does not fit in a float64: %w", n, ErrNumberRange)
+	}
+	return f, nil
+}
+
+// MarshalJSON encodes the number as a JSON string so no precision is lost by decoders that use floats
+func (n Number) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.String())
+}
+
+// UnmarshalJSON decodes a number from either a JSON string or a JSON number
+func (n *Number) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	number, err := ParseNumber(s)
+	if err != nil {
+		return err
+	}
+	*n = number
+	return nil
+}
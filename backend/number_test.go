@@ -0,0 +1,87 @@
+package backend
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func Test_ParseNumber(t *testing.T) {
+	tests := map[string]string{
+		"0":                                      "0",
+		"-0.000":                                 "0",
+		"42":                                     "42",
+		"+42":                                    "42",
+		"0042.500":                               "42.5",
+		"-.5":                                    "-0.5",
+		"1.5E+3":                                 "1500",
+		"1.5e-3":                                 "0.0015",
+		"12E-1":                                  "1.2",
+		"1E+125":                                 "1" + strings.Repeat("0", 125),
+		"1E-130":                                 "0." + strings.Repeat("0", 129) + "1",
+		"12345678901234567890123456789012345678": "12345678901234567890123456789012345678",
+	}
+
+	// + test
+	for s, expected := range tests {
+		n, err := ParseNumber(s)
+		if err != nil {
+			t.Errorf("%s: %s", s, err.Error())
+			continue
+		}
+		if n.String() != expected {
+			t.Errorf("%s: expected %s got %s", s, expected, n)
+		}
+	}
+	if MustParseNumber("1.50") != MustParseNumber("15E-1") {
+		t.Error("equal numbers should compare equal")
+	}
+
+	// - test
+	for _, s := range []string{"", "-", ".", "1.2.3", "abc", "1e", "0x10", "1 2"} {
+		if _, err := ParseNumber(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+	for _, s := range []string{"1E+126", "1E-131", "123456789012345678901234567890123456789"} {
+		if _, err := ParseNumber(s); !errors.Is(err, ErrNumberRange) {
+			t.Errorf("%s: expected ErrNumberRange got %v", s, err)
+		}
+	}
+}
+
+func Test_NumberConversions(t *testing.T) {
+
+	// + test
+	if i, err := NumberFromInt64(math.MinInt64).Int64(); err != nil || i != math.MinInt64 {
+		t.Errorf("expected %d got %d %v", int64(math.MinInt64), i, err)
+	}
+	if u, err := NumberFromUint64(math.MaxUint64).Uint64(); err != nil || u != math.MaxUint64 {
+		t.Errorf("expected %d got %d %v", uint64(math.MaxUint64), u, err)
+	}
+	n, err := NumberFromFloat64(0.1)
+	if err != nil || n.String() != "0.1" {
+		t.Errorf("expected 0.1 got %s %v", n, err)
+	}
+	if f, err := n.Float64(); err != nil || f != 0.1 {
+		t.Errorf("expected 0.1 got %v %v", f, err)
+	}
+
+	// - test
+	if _, err := NumberFromUint64(math.MaxUint64).Int64(); !errors.Is(err, ErrNumberRange) {
+		t.Errorf("expected ErrNumberRange got %v", err)
+	}
+	if _, err := NumberFromInt64(-1).Uint64(); !errors.Is(err, ErrNumberRange) {
+		t.Errorf("expected ErrNumberRange got %v", err)
+	}
+	if _, err := MustParseNumber("1.5").Int64(); !errors.Is(err, ErrNumberRange) {
+		t.Errorf("expected ErrNumberRange got %v", err)
+	}
+	if _, err := NumberFromFloat64(math.NaN()); !errors.Is(err, ErrNumberRange) {
+		t.Errorf("expected ErrNumberRange got %v", err)
+	}
+	if _, err := NumberFromFloat64(1e300); !errors.Is(err, ErrNumberRange) {
+		t.Errorf("expected ErrNumberRange got %v", err)
+	}
+}
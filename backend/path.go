@@ -1,3 +1,122 @@
 package backend
 
-type Path map[string]map[string]Properties
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Path is the chain of nodes and edges walked while resolving a slash separated path from a root node. Nodes
+// starts with the root followed by one node per path component and Edges[i] is the edge going from Nodes[i] to
+// Nodes[i+1].
+type Path struct {
+	Nodes []*PathNode `json:"nodes"`
+	Edges []*PathEdge `json:"edges"`
+}
+
+// PathNode is a node along a resolved path
+type PathNode struct {
+	ID         string      `json:"id"`
+	Properties *Properties `json:"properties"`
+}
+
+// PathEdge is an edge along a resolved path
+type PathEdge struct {
+	From       string      `json:"from"`
+	To         string      `json:"to"`
+	Name       string      `json:"name"`
+	Properties *Properties `json:"properties"`
+}
+
+// Last returns the node the path resolved to
+func (p *Path) Last() *PathNode {
+	return p.Nodes[len(p.Nodes)-1]
+}
+
+// FillNodes fills in the properties of the nodes along the path using get, which is usually a driver's GetNodes
+func (p *Path) FillNodes(ctx context.Context, get func(context.Context, *Nodes) (NodeResults, error)) error {
+	nodes := make(Nodes, len(p.Nodes))
+	for _, node := range p.Nodes {
+		nodes[node.ID] = node.Properties
+	}
+	results, err := get(ctx, &nodes)
+	if err != nil {
+		return err
+	}
+	if err := results.Err(); err != nil {
+		return err
+	}
+	for _, node := range p.Nodes {
+		node.Properties = nodes[node.ID]
+	}
+	return nil
+}
+
+// PathError is returned when a component of a path could not be resolved.  It is an ErrNotFound.
+type PathError struct {
+	Path      string
+	Component string
+	// Parent is the nid of the last node that was resolved before the missing component
+	Parent string
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("path %s: no entry named \"%s\" under %s", e.Path, e.Component, e.Parent)
+}
+
+// Is reports whether target is ErrNotFound
+func (e *PathError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// SplitPath breaks a slash separated path into its components.  Leading, trailing and repeated slashes are
+// ignored so "/a//b/" is the same as "a/b".  The "." and ".." components are not supported.
+func SplitPath(path string) ([]string, error) {
+	components := make([]string, 0, strings.Count(path, "/")+1)
+	for _, component := range strings.Split(path, "/") {
+		switch component {
+		case "":
+			continue
+		case ".", "..":
+			return nil, fmt.Errorf("path %s: relative component \"%s\" is not supported", path, component)
+		}
+		components = append(components, component)
+	}
+	return components, nil
+}
+
+// ResolvePath walks path one component at a time starting at root.  lookup is called with the nid of the
+// current node and the name of the next component and returns the nid and properties of the edge to the child
+// with that name, or an empty nid if there is none.  The properties of the nodes along the path are left empty
+// for the driver to fill in.  The walk stops as soon as the context is done.
+func ResolvePath(ctx context.Context, root, path string, lookup func(ctx context.Context, parent, name string) (string, *Properties, error)) (*Path, error) {
+
+	components, err := SplitPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Path{
+		Nodes: make([]*PathNode, 0, len(components)+1),
+		Edges: make([]*PathEdge, 0, len(components)),
+	}
+	p.Nodes = append(p.Nodes, &PathNode{ID: root, Properties: &Properties{}})
+
+	parent := root
+	for _, component := range components {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		nid, properties, err := lookup(ctx, parent, component)
+		if err != nil {
+			return nil, err
+		}
+		if nid == "" {
+			return nil, &PathError{Path: path, Component: component, Parent: parent}
+		}
+		p.Edges = append(p.Edges, &PathEdge{From: parent, To: nid, Name: component, Properties: properties})
+		p.Nodes = append(p.Nodes, &PathNode{ID: nid, Properties: &Properties{}})
+		parent = nid
+	}
+	return p, nil
+}
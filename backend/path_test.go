@@ -0,0 +1,75 @@
+package backend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func Test_SplitPath(t *testing.T) {
+	tests := map[string][]string{
+		"":           []string{},
+		"/":          []string{},
+		"/a/b/c.txt": []string{"a", "b", "c.txt"},
+		"a//b/":      []string{"a", "b"},
+	}
+	for path, expected := range tests {
+		components, err := SplitPath(path)
+		if err != nil {
+			t.Errorf("%s: %s", path, err.Error())
+		}
+		if !reflect.DeepEqual(components, expected) {
+			t.Errorf("%s: expected %v got %v", path, expected, components)
+		}
+	}
+
+	// - test
+	if _, err := SplitPath("/a/../b"); err == nil {
+		t.Error("relative components should be rejected")
+	}
+}
+
+func Test_ResolvePath(t *testing.T) {
+	children := map[string]map[string]string{
+		"root": {"a": "1"},
+		"1":    {"b": "2"},
+	}
+	lookup := func(ctx context.Context, parent, name string) (string, *Properties, error) {
+		nid := children[parent][name]
+		properties := &Properties{}
+		properties.SetString("name", name)
+		return nid, properties, nil
+	}
+
+	// + test
+	p, err := ResolvePath(context.Background(), "root", "/a/b", lookup)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(p.Nodes) != 3 || len(p.Edges) != 2 {
+		t.Fatalf("expected 3 nodes and 2 edges got %d and %d", len(p.Nodes), len(p.Edges))
+	}
+	if p.Last().ID != "2" {
+		t.Errorf("expected path to resolve to 2 got %s", p.Last().ID)
+	}
+	if p.Edges[1].From != "1" || p.Edges[1].To != "2" || p.Edges[1].Name != "b" {
+		t.Errorf("unexpected edge %#v", p.Edges[1])
+	}
+
+	// - test
+	_, err = ResolvePath(context.Background(), "root", "/a/c/d", lookup)
+	perr, ok := err.(*PathError)
+	if !ok {
+		t.Fatalf("expected a PathError got %v", err)
+	}
+	if perr.Component != "c" || perr.Parent != "1" {
+		t.Errorf("expected missing component c under 1 got %s under %s", perr.Component, perr.Parent)
+	}
+
+	// - test cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = ResolvePath(ctx, "root", "/a/b", lookup); err != context.Canceled {
+		t.Errorf("expected context.Canceled got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package backend
 
 import (
 	"fmt"
+	"time"
 )
 
 type PropertyType int
@@ -10,6 +11,13 @@ const (
 	StringProperty PropertyType = iota
 	NumberProperty
 	BinaryProperty
+	BoolProperty
+	FloatProperty
+	TimeProperty
+	// ListProperty values are a []*Property whose elements may be of different types
+	ListProperty
+	// MapProperty values are a *Properties
+	MapProperty
 )
 
 type Property struct {
@@ -22,11 +30,23 @@ type Properties map[string]*Property
 
 type Propertyers interface {
 	GetString(string) (string, error)
+	GetNumber(string) (Number, error)
 	GetInt(string) (int, error)
+	GetBool(string) (bool, error)
+	GetFloat(string) (float64, error)
+	GetTime(string) (time.Time, error)
+	GetList(string) ([]*Property, error)
+	GetMap(string) (*Properties, error)
 	SetKey(string, interface{})
 	SetString(string, string)
-	SetNumber(string, string)
+	SetNumber(string, Number)
+	SetInt(string, int)
 	SetBinary(string, []byte)
+	SetBool(string, bool)
+	SetFloat(string, float64)
+	SetTime(string, time.Time)
+	SetList(string, []*Property)
+	SetMap(string, *Properties)
 }
 
 // GetString pulls a string type out of Properties
@@ -42,12 +62,55 @@ func (p Properties) GetString(key string) (string, error) {
 	return "", fmt.Errorf("No such key: %s", key)
 }
 
-// GetInt pulls an int out of Properties
-func (p Properties) GetInt(key string) (int, error) {
+// GetNumber pulls a number out of Properties
+func (p Properties) GetNumber(key string) (Number, error) {
 	if property, ok := p[key]; ok {
 		switch property.Type {
 		case NumberProperty:
-			return property.Value.(int), nil
+			return property.Value.(Number), nil
+		default:
+			return Number{}, fmt.Errorf("Invalid %s parameter type: %T", key, property)
+		}
+	}
+	return Number{}, fmt.Errorf("No such key: %s", key)
+}
+
+// GetInt pulls a number out of Properties as an int.  Numbers that are not integers or do not fit in an int
+// are ErrNumberRange.
+func (p Properties) GetInt(key string) (int, error) {
+	n, err := p.GetNumber(key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := n.Int64()
+	if err != nil {
+		return 0, err
+	}
+	if int64(int(i)) != i {
+		return 0, fmt.Errorf("%s does not fit in an int: %w", n, ErrNumberRange)
+	}
+	return int(i), nil
+}
+
+// GetBool pulls a bool out of Properties
+func (p Properties) GetBool(key string) (bool, error) {
+	if property, ok := p[key]; ok {
+		switch property.Type {
+		case BoolProperty:
+			return property.Value.(bool), nil
+		default:
+			return false, fmt.Errorf("Invalid %s parameter type: %T", key, property)
+		}
+	}
+	return false, fmt.Errorf("No such key: %s", key)
+}
+
+// GetFloat pulls a float out of Properties
+func (p Properties) GetFloat(key string) (float64, error) {
+	if property, ok := p[key]; ok {
+		switch property.Type {
+		case FloatProperty:
+			return property.Value.(float64), nil
 		default:
 			return 0, fmt.Errorf("Invalid %s parameter type: %T", key, property)
 		}
@@ -55,6 +118,45 @@ func (p Properties) GetInt(key string) (int, error) {
 	return 0, fmt.Errorf("No such key: %s", key)
 }
 
+// GetTime pulls a time out of Properties
+func (p Properties) GetTime(key string) (time.Time, error) {
+	if property, ok := p[key]; ok {
+		switch property.Type {
+		case TimeProperty:
+			return property.Value.(time.Time), nil
+		default:
+			return time.Time{}, fmt.Errorf("Invalid %s parameter type: %T", key, property)
+		}
+	}
+	return time.Time{}, fmt.Errorf("No such key: %s", key)
+}
+
+// GetList pulls a list out of Properties
+func (p Properties) GetList(key string) ([]*Property, error) {
+	if property, ok := p[key]; ok {
+		switch property.Type {
+		case ListProperty:
+			return property.Value.([]*Property), nil
+		default:
+			return nil, fmt.Errorf("Invalid %s parameter type: %T", key, property)
+		}
+	}
+	return nil, fmt.Errorf("No such key: %s", key)
+}
+
+// GetMap pulls a map out of Properties
+func (p Properties) GetMap(key string) (*Properties, error) {
+	if property, ok := p[key]; ok {
+		switch property.Type {
+		case MapProperty:
+			return property.Value.(*Properties), nil
+		default:
+			return nil, fmt.Errorf("Invalid %s parameter type: %T", key, property)
+		}
+	}
+	return nil, fmt.Errorf("No such key: %s", key)
+}
+
 func (p *Properties) SetKey(key string, value interface{}) {
 	(*p)[key] = &Property{StringProperty, value}
 }
@@ -63,10 +165,63 @@ func (p *Properties) SetString(key string, value string) {
 	(*p)[key] = &Property{StringProperty, value}
 }
 
-func (p *Properties) SetNumber(key string, value string) {
+func (p *Properties) SetNumber(key string, value Number) {
 	(*p)[key] = &Property{NumberProperty, value}
 }
 
+func (p *Properties) SetInt(key string, value int) {
+	(*p)[key] = &Property{NumberProperty, NumberFromInt64(int64(value))}
+}
+
 func (p *Properties) SetBinary(key string, value []byte) {
 	(*p)[key] = &Property{BinaryProperty, value}
 }
+
+func (p *Properties) SetBool(key string, value bool) {
+	(*p)[key] = &Property{BoolProperty, value}
+}
+
+func (p *Properties) SetFloat(key string, value float64) {
+	(*p)[key] = &Property{FloatProperty, value}
+}
+
+func (p *Properties) SetTime(key string, value time.Time) {
+	(*p)[key] = &Property{TimeProperty, value}
+}
+
+func (p *Properties) SetList(key string, value []*Property) {
+	(*p)[key] = &Property{ListProperty, value}
+}
+
+func (p *Properties) SetMap(key string, value *Properties) {
+	(*p)[key] = &Property{MapProperty, value}
+}
+
+// Clone returns a copy of the properties that shares nothing with the original
+func (p Properties) Clone() *Properties {
+	clone := make(Properties, len(p))
+	for key, property := range p {
+		clone[key] = property.Clone()
+	}
+	return &clone
+}
+
+// Clone returns a copy of the property that shares nothing with the original, or nil for a nil property
+func (p *Property) Clone() *Property {
+	if p == nil {
+		return nil
+	}
+	switch value := p.Value.(type) {
+	case []byte:
+		return &Property{p.Type, append([]byte(nil), value...)}
+	case []*Property:
+		list := make([]*Property, len(value))
+		for i, element := range value {
+			list[i] = element.Clone()
+		}
+		return &Property{p.Type, list}
+	case *Properties:
+		return &Property{p.Type, value.Clone()}
+	}
+	return &Property{p.Type, p.Value}
+}
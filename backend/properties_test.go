@@ -1,14 +1,18 @@
 package backend
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+	"time"
+)
 
-func Test_StringKey(t *testing.T) {
-	props := Properties{
-		"str": "string a",
-	}
+func Test_GetString(t *testing.T) {
+	props := Properties{}
+	props.SetString("str", "string a")
+	props.SetInt("num", 1)
 
 	// + test
-	v, e := props.StringKey("str")
+	v, e := props.GetString("str")
 	if e != nil {
 		t.Error(e.Error())
 	}
@@ -17,11 +21,77 @@ func Test_StringKey(t *testing.T) {
 	}
 
 	// - test
-	v, e = props.StringKey("invalid")
+	v, e = props.GetString("invalid")
 	if e == nil {
 		t.Error()
 	}
 	if v != "" {
 		t.Error("invalid key should have returned an empty string")
 	}
+	if _, e = props.GetString("num"); e == nil {
+		t.Error("a number should not be returned as a string")
+	}
+}
+
+func Test_Getters(t *testing.T) {
+	now := time.Now()
+	list := []*Property{{StringProperty, "a"}, {BoolProperty, true}}
+	inner := &Properties{}
+	inner.SetFloat("f", 1.5)
+
+	props := Properties{}
+	props.SetBool("bool", true)
+	props.SetFloat("float", 2.5)
+	props.SetTime("time", now)
+	props.SetList("list", list)
+	props.SetMap("map", inner)
+
+	// + test
+	if b, e := props.GetBool("bool"); e != nil || !b {
+		t.Errorf("expected true got %v %v", b, e)
+	}
+	if f, e := props.GetFloat("float"); e != nil || f != 2.5 {
+		t.Errorf("expected 2.5 got %v %v", f, e)
+	}
+	if tm, e := props.GetTime("time"); e != nil || !tm.Equal(now) {
+		t.Errorf("expected %s got %v %v", now, tm, e)
+	}
+	if l, e := props.GetList("list"); e != nil || !reflect.DeepEqual(l, list) {
+		t.Errorf("expected %v got %v %v", list, l, e)
+	}
+	if m, e := props.GetMap("map"); e != nil || m != inner {
+		t.Errorf("expected %v got %v %v", inner, m, e)
+	}
+
+	// - test
+	if _, e := props.GetBool("float"); e == nil {
+		t.Error("a float should not be returned as a bool")
+	}
+	if _, e := props.GetMap("list"); e == nil {
+		t.Error("a list should not be returned as a map")
+	}
+	if _, e := props.GetTime("invalid"); e == nil {
+		t.Error("an invalid key should return an error")
+	}
+}
+
+func Test_Clone(t *testing.T) {
+	inner := &Properties{}
+	inner.SetBinary("b", []byte{1})
+	props := &Properties{}
+	props.SetList("list", []*Property{{MapProperty, inner}})
+
+	clone := props.Clone()
+	if !reflect.DeepEqual(props, clone) {
+		t.Fatalf("expected %v got %v", props, clone)
+	}
+
+	// changing the clone should leave the original alone
+	l, _ := clone.GetList("list")
+	m := l[0].Value.(*Properties)
+	(*m)["b"].Value.([]byte)[0] = 2
+	m.SetString("s", "x")
+	if (*inner)["b"].Value.([]byte)[0] != 1 || len(*inner) != 1 {
+		t.Errorf("the original was changed through the clone: %v", inner)
+	}
 }
@@ -0,0 +1,17 @@
+package backend
+
+import "context"
+
+// Provisioner is implemented by drivers whose backend needs indexes, constraints or tables set up before it can
+// be used.  Provision only creates what is missing so it is safe to call any number of times.
+type Provisioner interface {
+	Provision(ctx context.Context) (*ProvisionReport, error)
+}
+
+// ProvisionReport lists what a call to Provision found and did by name, e.g. the name of an index
+type ProvisionReport struct {
+	// Created is what was missing and has been created
+	Created []string
+	// Existing is what was already in place
+	Existing []string
+}
@@ -0,0 +1,97 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+)
+
+// NodeResults maps a nid to the outcome of a batch operation on that node.  A nil error means the operation
+// succeeded for the node, otherwise the error is one of the kinds in errors.go whenever the driver could tell
+// what went wrong, e.g. ErrNotFound or ErrAlreadyExists.
+type NodeResults map[string]error
+
+// Failed returns the nids the operation did not succeed for in sorted order
+func (r NodeResults) Failed() []string {
+	nids := make([]string, 0, len(r))
+	for nid, err := range r {
+		if err != nil {
+			nids = append(nids, nid)
+		}
+	}
+	sort.Strings(nids)
+	return nids
+}
+
+// FailedNodes returns the subset of nodes the operation did not succeed for so only those can be retried
+func (r NodeResults) FailedNodes(nodes *Nodes) *Nodes {
+	failed := make(Nodes)
+	for _, nid := range r.Failed() {
+		if properties, ok := (*nodes)[nid]; ok {
+			failed[nid] = properties
+		}
+	}
+	return &failed
+}
+
+// Err returns nil when the operation succeeded for every node, otherwise the error of the first failed nid
+func (r NodeResults) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", failed[0], r[failed[0]])
+}
+
+// EdgeResults maps a from nid to a to nid to the outcome of a batch operation on that edge, keyed the same way
+// as the Edges the operation was given.  A nil error means the operation succeeded for the edge.
+type EdgeResults map[string]map[string]error
+
+// Set records the outcome of the operation for one edge
+func (r EdgeResults) Set(fid, tid string, err error) {
+	if r[fid] == nil {
+		r[fid] = make(map[string]error)
+	}
+	r[fid][tid] = err
+}
+
+// Failed returns the edges the operation did not succeed for as pairs of nids sorted by from and then to
+func (r EdgeResults) Failed() [][2]string {
+	pairs := make([][2]string, 0, len(r))
+	for fid, tos := range r {
+		for tid, err := range tos {
+			if err != nil {
+				pairs = append(pairs, [2]string{fid, tid})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	return pairs
+}
+
+// FailedEdges returns the subset of edges the operation did not succeed for so only those can be retried
+func (r EdgeResults) FailedEdges(edges *Edges) *Edges {
+	failed := make(Edges)
+	for _, pair := range r.Failed() {
+		if properties, ok := (*edges)[pair[0]][pair[1]]; ok {
+			if failed[pair[0]] == nil {
+				failed[pair[0]] = make(map[string]*Properties)
+			}
+			failed[pair[0]][pair[1]] = properties
+		}
+	}
+	return &failed
+}
+
+// Err returns nil when the operation succeeded for every edge, otherwise the error of the first failed edge
+func (r EdgeResults) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s -> %s: %w", failed[0][0], failed[0][1], r[failed[0][0]][failed[0][1]])
+}
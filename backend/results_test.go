@@ -0,0 +1,49 @@
+package backend
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_NodeResults(t *testing.T) {
+	nodes := &Nodes{"1": &Properties{}, "2": &Properties{}, "3": &Properties{}}
+	results := NodeResults{"1": nil, "2": ErrNotFound, "3": ErrThrottled}
+
+	if failed := results.Failed(); !reflect.DeepEqual(failed, []string{"2", "3"}) {
+		t.Errorf("expected [2 3] got %v", failed)
+	}
+
+	retry := results.FailedNodes(nodes)
+	if len(*retry) != 2 || (*retry)["2"] != (*nodes)["2"] || (*retry)["3"] != (*nodes)["3"] {
+		t.Errorf("expected nodes 2 and 3 to be retried got %v", *retry)
+	}
+
+	if err := results.Err(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the first failure to be ErrNotFound got %v", err)
+	}
+	if err := (NodeResults{"1": nil}).Err(); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+}
+
+func Test_EdgeResults(t *testing.T) {
+	edges := &Edges{"a": {"1": &Properties{}, "2": &Properties{}}, "b": {"1": &Properties{}}}
+	results := EdgeResults{}
+	results.Set("a", "1", nil)
+	results.Set("a", "2", ErrConflict)
+	results.Set("b", "1", ErrNotFound)
+
+	if failed := results.Failed(); !reflect.DeepEqual(failed, [][2]string{{"a", "2"}, {"b", "1"}}) {
+		t.Errorf("expected [[a 2] [b 1]] got %v", failed)
+	}
+
+	retry := results.FailedEdges(edges)
+	if len((*retry)["a"]) != 1 || (*retry)["a"]["2"] != (*edges)["a"]["2"] || (*retry)["b"]["1"] != (*edges)["b"]["1"] {
+		t.Errorf("expected a -> 2 and b -> 1 to be retried got %v", *retry)
+	}
+
+	if err := results.Err(); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected the first failure to be ErrConflict got %v", err)
+	}
+}
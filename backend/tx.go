@@ -0,0 +1,24 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+)
+
+// Transactor is implemented by drivers that can run several operations as a single transaction
+type Transactor interface {
+	// WithTx calls fn with a Graph whose operations all belong to one transaction.  The transaction is committed
+	// when fn returns nil and rolled back when it returns an error, which WithTx then returns.  fn must only use
+	// the graph it is given, and calling WithTx on that graph joins the transaction that is already in progress.
+	WithTx(ctx context.Context, fn func(Graph) error) error
+}
+
+// WithTx runs fn in a transaction on g.  If the driver of g can not provide transactions fn is not called and
+// an ErrUnsupported is returned.
+func WithTx(ctx context.Context, g Graph, fn func(Graph) error) error {
+	t, ok := g.(Transactor)
+	if !ok {
+		return NewError(fmt.Sprintf("%T", g), "WithTx", ErrUnsupported, fmt.Errorf("Transactions are not supported"))
+	}
+	return t.WithTx(ctx, fn)
+}
@@ -1,53 +1,117 @@
 package ddb
 
 import (
-	"log"
+	"context"
 
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
 
-func (d *Driver) batchGet(table string, keys []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
+// batchGet gets the items with the given keys from the table.  Keys dynamodb leaves unprocessed are asked for
+// again, backing off between attempts, until the retry budget runs out.
+func (d *Driver) batchGet(ctx context.Context, table string, keys []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
 
 	items := make([]map[string]*dynamodb.AttributeValue, 0, len(keys))
+	b := d.backoff("BatchGetItem")
 	for {
 
-		resp, err := d.Connection.BatchGetItem(&dynamodb.BatchGetItemInput{
+		req, resp := d.Connection.BatchGetItemRequest(&dynamodb.BatchGetItemInput{
 			RequestItems: map[string]*dynamodb.KeysAndAttributes{
 				table: &dynamodb.KeysAndAttributes{
 					Keys: keys,
 				},
 			},
 		})
-		if err != nil {
-			log.Printf("batchGet: %s", err.Error())
+		if err := d.send(ctx, req); err != nil {
+			if !retryable(err) {
+				return nil, err
+			}
+			if err := b.wait(ctx, err, len(keys)); err != nil {
+				return nil, err
+			}
 			continue
 		}
 		items = append(items, resp.Responses[table]...)
 
 		// If we have no unprocessed items then we're good
-		if _, ok := resp.UnprocessedKeys[table]; !ok {
+		unprocessed, ok := resp.UnprocessedKeys[table]
+		if !ok || len(unprocessed.Keys) == 0 {
 			break
 		}
 
 		// handle the unprocessed items
-		keys = resp.UnprocessedKeys[table].Keys
+		keys = unprocessed.Keys
+		if err := b.wait(ctx, nil, len(keys)); err != nil {
+			return nil, err
+		}
 	}
 	return items, nil
 }
 
-func (d *Driver) batchWrite(groups []map[string]*dynamodb.BatchWriteItemInput) error {
-	for _, group := range groups {
+// groupWrites splits write requests on a table into groups that fit within a single BatchWriteItem call
+func groupWrites(table string, requests []*dynamodb.WriteRequest) []map[string][]*dynamodb.WriteRequest {
+	groups := make([]map[string][]*dynamodb.WriteRequest, 0, len(requests)/25+1)
+	for len(requests) > 25 {
+		groups = append(groups, map[string][]*dynamodb.WriteRequest{table: requests[:25]})
+		requests = requests[25:]
+	}
+	if len(requests) > 0 {
+		groups = append(groups, map[string][]*dynamodb.WriteRequest{table: requests})
+	}
+	return groups
+}
+
+// batchWrite writes the requests to the table in groups and returns the outcome of each request at the same
+// index as the request.  Unprocessed items and groups that fail for a reason that may go away are retried,
+// backing off between attempts, until the retry budget runs out.  When a group fails only the requests that
+// are still pending in it are given the error, since the ones dynamodb processed on an earlier attempt were
+// written.  An error is only returned when the context is done.
+func (d *Driver) batchWrite(ctx context.Context, table string, requests []*dynamodb.WriteRequest) ([]error, error) {
+	errs := make([]error, len(requests))
+	for i, group := range groupWrites(table, requests) {
+		// stop between groups when the caller has given up
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		index := make(map[string]int, len(group[table]))
+		for j, request := range group[table] {
+			index[d.writeKey(table, request)] = i*25 + j
+		}
+		b := d.backoff("BatchWriteItem")
 		for {
-			resp, err := d.send(d.Connection.BatchWriteItemRequest, group)
-			if err != nil {
-				return err.(error)
-			}
-			output := resp.(*dynamodb.BatchWriteItemOutput)
-			if output.UnprocessedItems == nil {
+			req, output := d.Connection.BatchWriteItemRequest(&dynamodb.BatchWriteItemInput{
+				RequestItems: group,
+			})
+			err := d.send(ctx, req)
+			if err == nil && len(output.UnprocessedItems[table]) == 0 {
 				break
 			}
-			group = output.UnprocessedItems
+			if err == nil {
+				group = output.UnprocessedItems
+				err = b.wait(ctx, nil, len(group[table]))
+			} else if retryable(err) {
+				err = b.wait(ctx, err, len(group[table]))
+			}
+			if err == nil {
+				continue
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			for _, request := range group[table] {
+				if j, ok := index[d.writeKey(table, request)]; ok {
+					errs[j] = err
+				}
+			}
+			break
 		}
 	}
-	return nil
+	return errs, nil
+}
+
+// writeKey identifies the item a write request puts or deletes
+func (d *Driver) writeKey(table string, request *dynamodb.WriteRequest) string {
+	if request.PutRequest != nil {
+		return d.itemKey(table, request.PutRequest.Item)
+	}
+	return d.itemKey(table, request.DeleteRequest.Key)
 }
@@ -0,0 +1,75 @@
+package ddb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func Test_writeKey(t *testing.T) {
+	d := &Driver{SourceID: "sid", NodeTableName: "nodes", EdgeTableName: "edges"}
+
+	// + test
+	item := d.edgeKey("1", "2")
+	item["name"] = &dynamodb.AttributeValue{S: aws.String("a")}
+	put := &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}}
+	del := &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: d.edgeKey("1", "2")}}
+	if d.writeKey("edges", put) != d.writeKey("edges", del) {
+		t.Errorf("expected a put and a delete of the same edge to have the same key")
+	}
+	node := &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: d.nodeKey("1")}}
+	if key := d.writeKey("nodes", node); key != "nodes|sid:1|1" {
+		t.Errorf("expected nodes|sid:1|1 got %s", key)
+	}
+
+	// - test
+	other := &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: d.edgeKey("1", "3")}}
+	if d.writeKey("edges", put) == d.writeKey("edges", other) {
+		t.Errorf("expected different edges to have different keys")
+	}
+}
+
+func Test_batchWrite(t *testing.T) {
+	calls := 0
+	d := &Driver{
+		SourceID:      "sid",
+		EdgeTableName: "edges",
+		retries:       retryPolicy{attempts: 3, baseDelay: time.Microsecond, maxDelay: time.Millisecond},
+		Connection: connection(func(r *request.Request) {
+			calls++
+			if calls > 1 {
+				r.Error = awserr.New("ValidationException", "refused", nil)
+				return
+			}
+			// the first attempt writes all but the last request
+			requests := r.Params.(*dynamodb.BatchWriteItemInput).RequestItems["edges"]
+			r.Data.(*dynamodb.BatchWriteItemOutput).UnprocessedItems = map[string][]*dynamodb.WriteRequest{
+				"edges": requests[len(requests)-1:],
+			}
+		}),
+	}
+	requests := []*dynamodb.WriteRequest{
+		{DeleteRequest: &dynamodb.DeleteRequest{Key: d.edgeKey("1", "2")}},
+		{DeleteRequest: &dynamodb.DeleteRequest{Key: d.edgeKey("1", "3")}},
+		{DeleteRequest: &dynamodb.DeleteRequest{Key: d.edgeKey("1", "4")}},
+	}
+
+	// + test
+	errs, err := d.batchWrite(context.Background(), "edges", requests)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if errs[0] != nil || errs[1] != nil {
+		t.Errorf("expected the written requests to succeed got %v", errs)
+	}
+
+	// - test
+	if errs[2] == nil {
+		t.Errorf("expected the request still pending to fail")
+	}
+}
@@ -0,0 +1,126 @@
+package ddb
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// capacityMeter holds the capacity consumed by the requests of a driver, keyed by sid and operation
+type capacityMeter struct {
+	sync.Mutex
+	usage map[[2]string]*backend.CapacityUsage
+}
+
+func newCapacityMeter() *capacityMeter {
+	return &capacityMeter{usage: make(map[[2]string]*backend.CapacityUsage)}
+}
+
+// ConsumedCapacity returns the capacity consumed by the requests of the driver, sorted by sid and then operation.
+// A driver built without newDriver has no meter and reports nothing.
+func (d *Driver) ConsumedCapacity() []backend.CapacityUsage {
+	return d.meter.snapshot()
+}
+
+// snapshot returns a copy of the usage, sorted by sid and then operation
+func (m *capacityMeter) snapshot() []backend.CapacityUsage {
+	if m == nil {
+		return nil
+	}
+	m.Lock()
+	defer m.Unlock()
+	usage := make([]backend.CapacityUsage, 0, len(m.usage))
+	for _, u := range m.usage {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].SourceID != usage[j].SourceID {
+			return usage[i].SourceID < usage[j].SourceID
+		}
+		return usage[i].Op < usage[j].Op
+	})
+	return usage
+}
+
+// record adds a request of the operation op and the capacity it consumed to the usage of sid.  The units of a
+// read operation are counted as read units and those of a write as write units, unless dynamodb breaks them
+// down itself.
+func (m *capacityMeter) record(sid, op string, read bool, consumed []*dynamodb.ConsumedCapacity) {
+	if m == nil {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	key := [2]string{sid, op}
+	u, ok := m.usage[key]
+	if !ok {
+		u = &backend.CapacityUsage{SourceID: sid, Op: op}
+		m.usage[key] = u
+	}
+	u.Requests++
+	for _, c := range consumed {
+		if c == nil {
+			continue
+		}
+		if c.ReadCapacityUnits != nil || c.WriteCapacityUnits != nil {
+			u.ReadUnits += aws.Float64Value(c.ReadCapacityUnits)
+			u.WriteUnits += aws.Float64Value(c.WriteCapacityUnits)
+			continue
+		}
+		if read {
+			u.ReadUnits += aws.Float64Value(c.CapacityUnits)
+		} else {
+			u.WriteUnits += aws.Float64Value(c.CapacityUnits)
+		}
+	}
+}
+
+// returnConsumedCapacity asks dynamodb to report the capacity the request consumes, if it is one that can
+func returnConsumedCapacity(req *request.Request) {
+	total := aws.String(dynamodb.ReturnConsumedCapacityTotal)
+	switch input := req.Params.(type) {
+	case *dynamodb.BatchGetItemInput:
+		input.ReturnConsumedCapacity = total
+	case *dynamodb.GetItemInput:
+		input.ReturnConsumedCapacity = total
+	case *dynamodb.BatchWriteItemInput:
+		input.ReturnConsumedCapacity = total
+	case *dynamodb.QueryInput:
+		input.ReturnConsumedCapacity = total
+	case *dynamodb.PutItemInput:
+		input.ReturnConsumedCapacity = total
+	case *dynamodb.UpdateItemInput:
+		input.ReturnConsumedCapacity = total
+	case *dynamodb.DeleteItemInput:
+		input.ReturnConsumedCapacity = total
+	case *dynamodb.TransactWriteItemsInput:
+		input.ReturnConsumedCapacity = total
+	}
+}
+
+// meterRequest records a request that was sent for the sid along with the capacity it reports having consumed.
+// Requests that do not consume capacity, such as those describing tables, are left out.
+func (m *capacityMeter) meterRequest(sid string, req *request.Request) {
+	switch output := req.Data.(type) {
+	case *dynamodb.BatchGetItemOutput:
+		m.record(sid, req.Operation.Name, true, output.ConsumedCapacity)
+	case *dynamodb.GetItemOutput:
+		m.record(sid, req.Operation.Name, true, []*dynamodb.ConsumedCapacity{output.ConsumedCapacity})
+	case *dynamodb.QueryOutput:
+		m.record(sid, req.Operation.Name, true, []*dynamodb.ConsumedCapacity{output.ConsumedCapacity})
+	case *dynamodb.BatchWriteItemOutput:
+		m.record(sid, req.Operation.Name, false, output.ConsumedCapacity)
+	case *dynamodb.PutItemOutput:
+		m.record(sid, req.Operation.Name, false, []*dynamodb.ConsumedCapacity{output.ConsumedCapacity})
+	case *dynamodb.UpdateItemOutput:
+		m.record(sid, req.Operation.Name, false, []*dynamodb.ConsumedCapacity{output.ConsumedCapacity})
+	case *dynamodb.DeleteItemOutput:
+		m.record(sid, req.Operation.Name, false, []*dynamodb.ConsumedCapacity{output.ConsumedCapacity})
+	case *dynamodb.TransactWriteItemsOutput:
+		m.record(sid, req.Operation.Name, false, output.ConsumedCapacity)
+	}
+}
@@ -0,0 +1,70 @@
+package ddb
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// sent returns a request of the operation as it looks once dynamodb has answered with output
+func sent(op string, input, output interface{}) *request.Request {
+	return &request.Request{Operation: &request.Operation{Name: op}, Params: input, Data: output}
+}
+
+func Test_meterRequest(t *testing.T) {
+	var _ backend.CapacityReporter = &Driver{}
+	units := func(n float64) *dynamodb.ConsumedCapacity {
+		return &dynamodb.ConsumedCapacity{TableName: aws.String("t"), CapacityUnits: aws.Float64(n)}
+	}
+
+	// + test
+	input := &dynamodb.QueryInput{}
+	returnConsumedCapacity(sent("Query", input, nil))
+	if aws.StringValue(input.ReturnConsumedCapacity) != dynamodb.ReturnConsumedCapacityTotal {
+		t.Errorf("expected the query to ask for the consumed capacity")
+	}
+
+	d := &Driver{SourceID: "a", meter: newCapacityMeter()}
+	d.meter.meterRequest("a", sent("Query", input, &dynamodb.QueryOutput{ConsumedCapacity: units(1.5)}))
+	d.meter.meterRequest("a", sent("Query", input, &dynamodb.QueryOutput{ConsumedCapacity: units(0.5)}))
+	d.meter.meterRequest("a", sent("BatchWriteItem", nil, &dynamodb.BatchWriteItemOutput{
+		ConsumedCapacity: []*dynamodb.ConsumedCapacity{units(2), units(3)},
+	}))
+	d.meter.meterRequest("a", sent("TransactWriteItems", nil, &dynamodb.TransactWriteItemsOutput{
+		ConsumedCapacity: []*dynamodb.ConsumedCapacity{{ReadCapacityUnits: aws.Float64(1), WriteCapacityUnits: aws.Float64(4)}},
+	}))
+
+	usage := d.ConsumedCapacity()
+	expected := []backend.CapacityUsage{
+		{SourceID: "a", Op: "BatchWriteItem", Requests: 1, WriteUnits: 5},
+		{SourceID: "a", Op: "Query", Requests: 2, ReadUnits: 2},
+		{SourceID: "a", Op: "TransactWriteItems", Requests: 1, ReadUnits: 1, WriteUnits: 4},
+	}
+	if len(usage) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, usage)
+	}
+	for i := range expected {
+		if usage[i] != expected[i] {
+			t.Errorf("expected %+v got %+v", expected[i], usage[i])
+		}
+	}
+
+	// - test
+	// a request without an output that consumes capacity is left out, and drivers are kept apart
+	other := &Driver{SourceID: "b", meter: newCapacityMeter()}
+	other.meter.meterRequest("b", sent("DescribeTable", nil, &dynamodb.DescribeTableOutput{}))
+	if usage := other.ConsumedCapacity(); len(usage) != 0 {
+		t.Errorf("expected no usage got %v", usage)
+	}
+	if usage := d.ConsumedCapacity(); len(usage) != len(expected) {
+		t.Errorf("expected the usage of the first driver unchanged got %v", usage)
+	}
+	// a driver without a meter records nothing
+	(&Driver{}).meter.meterRequest("c", sent("Query", input, &dynamodb.QueryOutput{ConsumedCapacity: units(1)}))
+	if usage := (&Driver{}).ConsumedCapacity(); usage != nil {
+		t.Errorf("expected no usage without a meter got %v", usage)
+	}
+}
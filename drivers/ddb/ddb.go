@@ -1,10 +1,21 @@
 package ddb
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
-	"reflect"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/sir-wiggles/bcfs/backend"
 )
@@ -22,6 +33,8 @@ var (
 	EDGE_ATTR_NAME   = aws.String("name")
 	EDGE_LSI_NAME    = aws.String("name-index")
 	EDGE_GSI_REVERSE = aws.String("sid_to-sid_from-index")
+	// EDGE_NAME_LOCK separates the sid from the name in the to key of the item locking an edge name, see nameKey
+	EDGE_NAME_LOCK = "#name#"
 
 	// Node table parameters
 	NODE_TABLE_NAME     = aws.String("fs-node")
@@ -29,6 +42,9 @@ var (
 	NODE_RANGE          = aws.String("nid")
 	NODE_ATTR_BLOCKLIST = aws.String("blocklist_id")
 	NODE_GSI_BLOCKLIST  = aws.String("sid_nid-blocklist_id-index")
+
+	// The type hint of a property is stored in an attribute named with this prefix followed by the property name
+	ATTR_TYPE_PREFIX = "_type:"
 )
 
 func init() {
@@ -41,47 +57,494 @@ type Driver struct {
 	SourceID      string
 	NodeTableName string
 	EdgeTableName string
+
+	// capacity is the provisioned throughput of the tables created by Provision, nil to bill them per request
+	capacity *dynamodb.ProvisionedThroughput
+	// retries is the retry budget of a single request
+	retries retryPolicy
+	// concurrency is how many requests a single call sends at once when it fans out
+	concurrency int
+	// meter holds the capacity consumed by the requests of the driver
+	meter *capacityMeter
 }
 
+// Defaults used when the config leaves a key out
+var (
+	DEFAULT_SOURCE_ID   = "default"
+	DEFAULT_TIMEOUT     = 10
+	DEFAULT_MAX_RETRIES = 3
+)
+
+// newDriver creates a driver with a dynamodb client set up from the config.  The region is required, and key and
+// secret must be given together.  Without them the credentials are looked up the usual aws way: the
+// environment, the shared credentials file and then the instance role.  An endpoint points the client at
+// something other than aws, such as DynamoDB Local.
 func newDriver(c *backend.Config) (backend.Graph, error) {
-	return &Driver{}, nil
+
+	// empty values come from keys left blank in the config file and count as missing
+	value := func(key, def string) string {
+		if c.HasKey(key) && c.StringKey(key) != "" {
+			return c.StringKey(key)
+		}
+		return def
+	}
+	number := func(key string, def int) int {
+		if c.HasKey(key) {
+			return c.IntKey(key)
+		}
+		return def
+	}
+
+	var problems []string
+	region := value("region", "")
+	if region == "" {
+		problems = append(problems, "region is required")
+	}
+	key, secret := value("key", ""), value("secret", "")
+	if (key == "") != (secret == "") {
+		problems = append(problems, "key and secret must be given together")
+	}
+	timeout := number("timeout", DEFAULT_TIMEOUT)
+	if timeout < 1 {
+		problems = append(problems, fmt.Sprintf("timeout must be at least 1 second, got %d", timeout))
+	}
+	retries := number("max-retries", DEFAULT_MAX_RETRIES)
+	if retries < 0 {
+		problems = append(problems, fmt.Sprintf("max-retries can not be negative, got %d", retries))
+	}
+	sid := value("sid", DEFAULT_SOURCE_ID)
+	if strings.Contains(sid, ":") {
+		// the sid is split from the nid on the first colon of a key
+		problems = append(problems, fmt.Sprintf("sid %s can not contain a colon", sid))
+	}
+	nodeTable, edgeTable := value("node-table", *NODE_TABLE_NAME), value("edge-table", *EDGE_TABLE_NAME)
+	if nodeTable == edgeTable {
+		problems = append(problems, "node-table and edge-table must be different tables")
+	}
+	policy := retryPolicy{
+		attempts:  number("retry-attempts", DEFAULT_RETRY_ATTEMPTS),
+		baseDelay: time.Duration(number("retry-base-delay", int(DEFAULT_RETRY_BASE_DELAY/time.Millisecond))) * time.Millisecond,
+		maxDelay:  time.Duration(number("retry-max-delay", int(DEFAULT_RETRY_MAX_DELAY/time.Millisecond))) * time.Millisecond,
+	}
+	if policy.attempts < 1 {
+		problems = append(problems, fmt.Sprintf("retry-attempts must be at least 1, got %d", policy.attempts))
+	}
+	if policy.baseDelay <= 0 || policy.maxDelay < policy.baseDelay {
+		problems = append(problems, "retry-base-delay must be positive and no more than retry-max-delay")
+	}
+	concurrency := number("concurrency", DEFAULT_CONCURRENCY)
+	if concurrency < 1 {
+		problems = append(problems, fmt.Sprintf("concurrency must be at least 1, got %d", concurrency))
+	}
+	read, write := number("read-capacity", 0), number("write-capacity", 0)
+	if (read == 0) != (write == 0) || read < 0 || write < 0 {
+		problems = append(problems, "read-capacity and write-capacity must both be positive, or both be 0 to bill per request")
+	}
+	if len(problems) > 0 {
+		return nil, backend.NewError(PACKAGE_NAME, "newDriver", nil,
+			fmt.Errorf("Invalid ddb config: %s", strings.Join(problems, "; ")))
+	}
+
+	cfg := &aws.Config{
+		Region:     aws.String(region),
+		MaxRetries: aws.Int(retries),
+		HTTPClient: &http.Client{Timeout: time.Duration(timeout) * time.Second},
+	}
+	if endpoint := value("endpoint", ""); endpoint != "" {
+		cfg.Endpoint = aws.String(endpoint)
+	}
+	if key != "" {
+		cfg.Credentials = credentials.NewStaticCredentials(key, secret, value("session-token", ""))
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, backend.NewError(PACKAGE_NAME, "newDriver", nil, err)
+	}
+
+	d := &Driver{
+		Connection:    dynamodb.New(sess),
+		SourceID:      sid,
+		NodeTableName: nodeTable,
+		EdgeTableName: edgeTable,
+		retries:       policy,
+		concurrency:   concurrency,
+		meter:         newCapacityMeter(),
+	}
+	if read > 0 {
+		d.capacity = &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(int64(read)),
+			WriteCapacityUnits: aws.Int64(int64(write)),
+		}
+	}
+	return d, nil
 }
 
-func getFieldOfInterest(item *dynamodb.AttributeValue) string {
-	v := reflect.ValueOf(item).Elem()
-	t := v.Type()
-	n := t.NumField()
+// nodeKey returns the primary key of a node in the node table
+func (d *Driver) nodeKey(nid string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		*NODE_HASH:  &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("%s:%s", d.SourceID, nid))},
+		*NODE_RANGE: &dynamodb.AttributeValue{S: aws.String(nid)},
+	}
+}
 
-	for i := 0; i < n; i++ {
-		f := v.Field(i)
-		switch f.Kind() {
-		case reflect.Ptr:
-			if f.IsNil() {
-				continue
-			}
-		case reflect.Struct:
-			continue
-		case reflect.Slice, reflect.Map:
-			if reflect.ValueOf(f.Interface()).Len() == 0 {
-				continue
+// edgeKey returns the primary key of the edge going from fid to tid in the edge table
+func (d *Driver) edgeKey(fid, tid string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		*EDGE_HASH:  &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("%s:%s", d.SourceID, fid))},
+		*EDGE_RANGE: &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("%s:%s", d.SourceID, tid))},
+	}
+}
+
+// edgeIDs returns the from and to nids of an edge item, stripping the source id from the keys
+func edgeIDs(item map[string]*dynamodb.AttributeValue) (string, string) {
+	fid := strings.SplitN(*item[*EDGE_HASH].S, ":", 2)[1]
+	tid := strings.SplitN(*item[*EDGE_RANGE].S, ":", 2)[1]
+	return fid, tid
+}
+
+// itemKey identifies an item of the node or edge table by the table and the values of its key attributes
+func (d *Driver) itemKey(table string, item map[string]*dynamodb.AttributeValue) string {
+	hash, rng := EDGE_HASH, EDGE_RANGE
+	if table == d.NodeTableName {
+		hash, rng = NODE_HASH, NODE_RANGE
+	}
+	key := table
+	for _, name := range []*string{hash, rng} {
+		key += "|"
+		if value, ok := item[*name]; ok {
+			key += aws.StringValue(value.S)
+		}
+	}
+	return key
+}
+
+// marshalProperty converts a backend property into a dynamodb attribute.  Floats are stored as N and times as
+// RFC 3339 strings, so a hint naming their backend type is returned along with them to tell them apart from
+// numbers and strings when they are read back.  The hint of a list or map is a map of the hints of its elements
+// and the hint is nil whenever the type follows from the attribute alone.
+func marshalProperty(key string, property *backend.Property) (*dynamodb.AttributeValue, *dynamodb.AttributeValue, error) {
+	switch property.Type {
+	case backend.StringProperty:
+		if value, ok := property.Value.(string); ok {
+			return &dynamodb.AttributeValue{S: aws.String(value)}, nil, nil
+		}
+	case backend.NumberProperty:
+		if value, ok := property.Value.(backend.Number); ok {
+			return &dynamodb.AttributeValue{N: aws.String(value.String())}, nil, nil
+		}
+	case backend.BinaryProperty:
+		if value, ok := property.Value.([]byte); ok {
+			return &dynamodb.AttributeValue{B: value}, nil, nil
+		}
+	case backend.BoolProperty:
+		if value, ok := property.Value.(bool); ok {
+			return &dynamodb.AttributeValue{BOOL: aws.Bool(value)}, nil, nil
+		}
+	case backend.FloatProperty:
+		if value, ok := property.Value.(float64); ok && !math.IsNaN(value) && !math.IsInf(value, 0) {
+			return &dynamodb.AttributeValue{N: aws.String(strconv.FormatFloat(value, 'g', -1, 64))},
+				&dynamodb.AttributeValue{S: aws.String(backend.FloatProperty.String())}, nil
+		}
+	case backend.TimeProperty:
+		if value, ok := property.Value.(time.Time); ok {
+			return &dynamodb.AttributeValue{S: aws.String(value.Format(time.RFC3339Nano))},
+				&dynamodb.AttributeValue{S: aws.String(backend.TimeProperty.String())}, nil
+		}
+	case backend.ListProperty:
+		if value, ok := property.Value.([]*backend.Property); ok {
+			list := make([]*dynamodb.AttributeValue, len(value))
+			hints := make(map[string]*dynamodb.AttributeValue)
+			for i, element := range value {
+				av, hint, err := marshalProperty(fmt.Sprintf("%s[%d]", key, i), element)
+				if err != nil {
+					return nil, nil, err
+				}
+				list[i] = av
+				if hint != nil {
+					hints[strconv.Itoa(i)] = hint
+				}
 			}
-		case reflect.Bool:
-			if f.IsNil() {
-				continue
+			return &dynamodb.AttributeValue{L: list}, containerHint(hints), nil
+		}
+	case backend.MapProperty:
+		if value, ok := property.Value.(*backend.Properties); ok {
+			m := make(map[string]*dynamodb.AttributeValue, len(*value))
+			hints := make(map[string]*dynamodb.AttributeValue)
+			for k, element := range *value {
+				av, hint, err := marshalProperty(key+"."+k, element)
+				if err != nil {
+					return nil, nil, err
+				}
+				m[k] = av
+				if hint != nil {
+					hints[k] = hint
+				}
 			}
+			return &dynamodb.AttributeValue{M: m}, containerHint(hints), nil
 		}
-		return reflect.Indirect(reflect.ValueOf(item)).Type().Field(i).Name
 	}
-	return ""
+	return nil, nil, fmt.Errorf("Invalid %s property: %T", key, property.Value)
+}
+
+// containerHint returns the hint of a list or map given the hints of its elements
+func containerHint(hints map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if len(hints) == 0 {
+		return nil
+	}
+	return &dynamodb.AttributeValue{M: hints}
 }
 
-func (d Driver) send(sender func(interface{}) (interface{}, interface{}), request interface{}) (interface{}, interface{}) {
-	for {
-		resp, err := sender(request)
+// typeAttr returns the name of the attribute holding the type hint of a property
+func typeAttr(key string) string {
+	return ATTR_TYPE_PREFIX + key
+}
+
+// marshalProperties converts backend properties into a dynamodb item.  Properties given as nil are left out.
+func marshalProperties(properties *backend.Properties) (map[string]*dynamodb.AttributeValue, error) {
+	item := make(map[string]*dynamodb.AttributeValue, len(*properties))
+	for key, property := range *properties {
+		if property == nil {
+			continue
+		}
+		value, hint, err := marshalProperty(key, property)
 		if err != nil {
-			// TODO: handle various ddb error codes
 			return nil, err
 		}
-		return resp, nil
+		item[key] = value
+		if hint != nil {
+			item[typeAttr(key)] = hint
+		}
+	}
+	return item, nil
+}
+
+// setExpression builds an update expression setting the given properties, leaving out the key attributes.  A
+// property given as nil is removed instead.  The type hint of every property is set or removed along with it so
+// a stale hint never outlives a type change.  The returned expression is empty if there is nothing to change,
+// and the values are nil if nothing is set since dynamodb refuses an empty map of them.
+func setExpression(properties *backend.Properties, keys ...string) (*string, map[string]*string, map[string]*dynamodb.AttributeValue, error) {
+
+	fields := make([]string, 0, len(*properties))
+outer:
+	for field := range *properties {
+		for _, key := range keys {
+			if field == key {
+				continue outer
+			}
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil, nil, nil, nil
+	}
+	sort.Strings(fields)
+
+	sets := make([]string, 0, len(fields))
+	removes := make([]string, 0, len(fields))
+	names := make(map[string]*string, 2*len(fields))
+	values := make(map[string]*dynamodb.AttributeValue, len(fields))
+	for i, field := range fields {
+		name := fmt.Sprintf("#p%d", i)
+		hintName := fmt.Sprintf("#t%d", i)
+		names[name] = aws.String(field)
+		names[hintName] = aws.String(typeAttr(field))
+
+		property := (*properties)[field]
+		if property == nil {
+			removes = append(removes, name, hintName)
+			continue
+		}
+		value, hint, err := marshalProperty(field, property)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		placeholder := fmt.Sprintf(":p%d", i)
+		values[placeholder] = value
+		sets = append(sets, fmt.Sprintf("%s = %s", name, placeholder))
+
+		if hint == nil {
+			removes = append(removes, hintName)
+			continue
+		}
+		hintPlaceholder := fmt.Sprintf(":t%d", i)
+		values[hintPlaceholder] = hint
+		sets = append(sets, fmt.Sprintf("%s = %s", hintName, hintPlaceholder))
+	}
+
+	clauses := make([]string, 0, 2)
+	if len(sets) > 0 {
+		clauses = append(clauses, "SET "+strings.Join(sets, ", "))
+	}
+	if len(removes) > 0 {
+		clauses = append(clauses, "REMOVE "+strings.Join(removes, ", "))
+	}
+	if len(values) == 0 {
+		values = nil
+	}
+	return aws.String(strings.Join(clauses, " ")), names, values, nil
+}
+
+// keyChange returns an error of kind backend.ErrConflict if the properties would change one of the key
+// attributes of an item.  Key attributes given with the value they already have, as they come back from a get,
+// are fine.
+func keyChange(op string, properties *backend.Properties, key map[string]*dynamodb.AttributeValue) error {
+	for attribute, value := range key {
+		property, ok := (*properties)[attribute]
+		if !ok {
+			continue
+		}
+		if property == nil || property.Type != backend.StringProperty || property.Value != aws.StringValue(value.S) {
+			return backend.NewError(PACKAGE_NAME, op, backend.ErrConflict,
+				fmt.Errorf("The key attribute %s can not be altered", attribute))
+		}
+	}
+	return nil
+}
+
+// unmarshalItem copies the attributes of a dynamodb item into backend properties using the type hints stored
+// along with them.  NULL attributes are left out.
+func unmarshalItem(item map[string]*dynamodb.AttributeValue, properties *backend.Properties) error {
+	for key, value := range item {
+		if strings.HasPrefix(key, ATTR_TYPE_PREFIX) {
+			continue
+		}
+		property, err := unmarshalAttribute(key, value, item[typeAttr(key)])
+		if err != nil {
+			return err
+		}
+		if property != nil {
+			(*properties)[key] = property
+		}
+	}
+	return nil
+}
+
+// unmarshalAttribute converts a dynamodb attribute back into a backend property given the hint written along
+// with it by marshalProperty, if any.  It returns nil for NULL.  String, number and binary sets, which bcfs never
+// writes itself, are read as lists.
+func unmarshalAttribute(key string, value, hint *dynamodb.AttributeValue) (*backend.Property, error) {
+
+	if hint != nil && hint.S != nil {
+		switch {
+		case *hint.S == backend.FloatProperty.String() && value.N != nil:
+			f, err := strconv.ParseFloat(*value.N, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid %s float: %w", key, err)
+			}
+			return &backend.Property{Type: backend.FloatProperty, Value: f}, nil
+		case *hint.S == backend.TimeProperty.String() && value.S != nil:
+			t, err := time.Parse(time.RFC3339Nano, *value.S)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid %s time: %w", key, err)
+			}
+			return &backend.Property{Type: backend.TimeProperty, Value: t}, nil
+		}
+	}
+
+	switch {
+	case value.S != nil:
+		return &backend.Property{Type: backend.StringProperty, Value: *value.S}, nil
+	case value.N != nil:
+		n, err := backend.ParseNumber(*value.N)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s number: %w", key, err)
+		}
+		return &backend.Property{Type: backend.NumberProperty, Value: n}, nil
+	case value.B != nil:
+		return &backend.Property{Type: backend.BinaryProperty, Value: value.B}, nil
+	case value.BOOL != nil:
+		return &backend.Property{Type: backend.BoolProperty, Value: *value.BOOL}, nil
+	case value.NULL != nil:
+		return nil, nil
+	case value.L != nil:
+		list := make([]*backend.Property, 0, len(value.L))
+		for i, element := range value.L {
+			property, err := unmarshalAttribute(fmt.Sprintf("%s[%d]", key, i), element, elementHint(hint, strconv.Itoa(i)))
+			if err != nil {
+				return nil, err
+			}
+			if property != nil {
+				list = append(list, property)
+			}
+		}
+		return &backend.Property{Type: backend.ListProperty, Value: list}, nil
+	case value.M != nil:
+		m := make(backend.Properties, len(value.M))
+		for k, element := range value.M {
+			property, err := unmarshalAttribute(key+"."+k, element, elementHint(hint, k))
+			if err != nil {
+				return nil, err
+			}
+			if property != nil {
+				m[k] = property
+			}
+		}
+		return &backend.Property{Type: backend.MapProperty, Value: &m}, nil
+	case value.SS != nil:
+		list := make([]*backend.Property, len(value.SS))
+		for i, s := range value.SS {
+			list[i] = &backend.Property{Type: backend.StringProperty, Value: *s}
+		}
+		return &backend.Property{Type: backend.ListProperty, Value: list}, nil
+	case value.NS != nil:
+		list := make([]*backend.Property, len(value.NS))
+		for i, s := range value.NS {
+			n, err := backend.ParseNumber(*s)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid %s number: %w", key, err)
+			}
+			list[i] = &backend.Property{Type: backend.NumberProperty, Value: n}
+		}
+		return &backend.Property{Type: backend.ListProperty, Value: list}, nil
+	case value.BS != nil:
+		list := make([]*backend.Property, len(value.BS))
+		for i, b := range value.BS {
+			list[i] = &backend.Property{Type: backend.BinaryProperty, Value: b}
+		}
+		return &backend.Property{Type: backend.ListProperty, Value: list}, nil
+	}
+	return nil, fmt.Errorf("no field found for %s", key)
+}
+
+// elementHint returns the hint of an element of a list or map given the hint of the container
+func elementHint(hint *dynamodb.AttributeValue, key string) *dynamodb.AttributeValue {
+	if hint == nil || hint.M == nil {
+		return nil
+	}
+	return hint.M[key]
+}
+
+// send sends the request once, cancelling it when the context is done.  Errors are mapped into backend errors so
+// callers can tell with retryable whether the request is worth making again.  The capacity the request consumes
+// is added to the usage of the sid.
+func (d Driver) send(ctx context.Context, req *request.Request) error {
+	req.SetContext(ctx)
+	returnConsumedCapacity(req)
+	err := req.Send()
+	d.meter.meterRequest(d.SourceID, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return newError(req.Operation.Name, err)
+	}
+	return nil
+}
+
+// putNew puts the item into the table unless an item with the same key is already there, which is left
+// unchanged and reported as backend.ErrAlreadyExists.  hash is the name of the hash key attribute.
+// BatchWriteItem can not take conditions so every item is its own PutItem.
+func (d *Driver) putNew(ctx context.Context, table string, item map[string]*dynamodb.AttributeValue, hash string) error {
+	err := d.retry(ctx, "PutItem", func() error {
+		req, _ := d.Connection.PutItemRequest(&dynamodb.PutItemInput{
+			TableName:           aws.String(table),
+			Item:                item,
+			ConditionExpression: aws.String(fmt.Sprintf("attribute_not_exists(%s)", hash)),
+		})
+		return d.send(ctx, req)
+	})
+	if conditionFailed(err) {
+		return backend.NewError(PACKAGE_NAME, "PutItem", backend.ErrAlreadyExists, errors.Unwrap(err))
 	}
+	return err
 }
@@ -0,0 +1,59 @@
+package ddb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+func Test_newDriver(t *testing.T) {
+
+	// + test
+	g, err := newDriver(&backend.Config{
+		"key":        "key",
+		"secret":     "secret",
+		"region":     "us-west-2",
+		"endpoint":   "http://localhost:8000",
+		"node-table": "nodes",
+		"sid":        "abc",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	d := g.(*Driver)
+	if d.SourceID != "abc" || d.NodeTableName != "nodes" || d.EdgeTableName != *EDGE_TABLE_NAME {
+		t.Errorf("unexpected driver %+v", d)
+	}
+	if endpoint := d.Connection.Endpoint; endpoint != "http://localhost:8000" {
+		t.Errorf("expected the local endpoint got %s", endpoint)
+	}
+	if d.concurrency != DEFAULT_CONCURRENCY {
+		t.Errorf("expected the default concurrency got %d", d.concurrency)
+	}
+	if d.retries.attempts != DEFAULT_RETRY_ATTEMPTS || d.retries.maxDelay != DEFAULT_RETRY_MAX_DELAY {
+		t.Errorf("expected the default retry budget got %+v", d.retries)
+	}
+
+	// - test
+	for _, tc := range []struct {
+		cfg     backend.Config
+		problem string
+	}{
+		{backend.Config{"region": ""}, "region is required"},
+		{backend.Config{"region": "us-west-2", "key": "key"}, "key and secret"},
+		{backend.Config{"region": "us-west-2", "timeout": 0}, "timeout"},
+		{backend.Config{"region": "us-west-2", "max-retries": -1}, "max-retries"},
+		{backend.Config{"region": "us-west-2", "sid": "a:b"}, "colon"},
+		{backend.Config{"region": "us-west-2", "node-table": "t", "edge-table": "t"}, "different tables"},
+		{backend.Config{"region": "us-west-2", "read-capacity": 5}, "read-capacity and write-capacity"},
+		{backend.Config{"region": "us-west-2", "retry-attempts": 0}, "retry-attempts"},
+		{backend.Config{"region": "us-west-2", "concurrency": 0}, "concurrency"},
+		{backend.Config{"region": "us-west-2", "retry-base-delay": 100, "retry-max-delay": 10}, "retry-base-delay"},
+	} {
+		_, err := newDriver(&tc.cfg)
+		if err == nil || !strings.Contains(err.Error(), tc.problem) {
+			t.Errorf("%v: expected an error about %s got %v", tc.cfg, tc.problem, err)
+		}
+	}
+}
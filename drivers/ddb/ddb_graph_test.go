@@ -0,0 +1,22 @@
+package ddb
+
+import (
+	"testing"
+
+	"github.com/sir-wiggles/bcfs/backend"
+	"github.com/sir-wiggles/bcfs/backend/graphtest"
+)
+
+func Test_Graph(t *testing.T) {
+
+	env := setup(t)
+
+	graphtest.Run(t, func(c *backend.Config) (backend.Graph, error) {
+		return &Driver{
+			Connection:    env.db,
+			SourceID:      c.StringKey("sid"),
+			NodeTableName: *NODE_TABLE_NAME,
+			EdgeTableName: *EDGE_TABLE_NAME,
+		}, nil
+	}, backend.Config{"name": PACKAGE_NAME})
+}
@@ -10,59 +10,10 @@ import (
 
 func createNodeTable(t *testing.T, db *dynamodb.DynamoDB) {
 
-	_, err := db.CreateTable(&dynamodb.CreateTableInput{
-		TableName: NODE_TABLE_NAME,
-		AttributeDefinitions: []*dynamodb.AttributeDefinition{
-			&dynamodb.AttributeDefinition{
-				AttributeName: NODE_HASH,
-				AttributeType: aws.String("S"),
-			},
-			&dynamodb.AttributeDefinition{
-				AttributeName: NODE_RANGE,
-				AttributeType: aws.String("S"),
-			},
-			&dynamodb.AttributeDefinition{
-				AttributeName: NODE_ATTR_BLOCKLIST,
-				AttributeType: aws.String("S"),
-			},
-		},
-		KeySchema: []*dynamodb.KeySchemaElement{
-			&dynamodb.KeySchemaElement{
-				AttributeName: NODE_HASH,
-				KeyType:       aws.String("HASH"),
-			},
-			&dynamodb.KeySchemaElement{
-				AttributeName: NODE_RANGE,
-				KeyType:       aws.String("RANGE"),
-			},
-		},
-		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
-			&dynamodb.GlobalSecondaryIndex{
-				IndexName: NODE_GSI_BLOCKLIST,
-				KeySchema: []*dynamodb.KeySchemaElement{
-					&dynamodb.KeySchemaElement{
-						AttributeName: NODE_HASH,
-						KeyType:       aws.String("HASH"),
-					},
-					&dynamodb.KeySchemaElement{
-						AttributeName: NODE_ATTR_BLOCKLIST,
-						KeyType:       aws.String("RANGE"),
-					},
-				},
-				Projection: &dynamodb.Projection{
-					ProjectionType: aws.String(dynamodb.ProjectionTypeAll),
-				},
-				ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
-					ReadCapacityUnits:  aws.Int64(10),
-					WriteCapacityUnits: aws.Int64(10),
-				},
-			},
-		},
-		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
-			ReadCapacityUnits:  aws.Int64(10),
-			WriteCapacityUnits: aws.Int64(10),
-		},
-	})
+	_, err := db.CreateTable(nodeTableInput(*NODE_TABLE_NAME, &dynamodb.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(10),
+		WriteCapacityUnits: aws.Int64(10),
+	}))
 	if err != nil {
 		t.Fatalf("create node table: %s", err.Error())
 	}
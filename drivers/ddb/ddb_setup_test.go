@@ -2,11 +2,13 @@ package ddb
 
 import (
 	"net/http"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
 
@@ -15,8 +17,14 @@ type env struct {
 	t  *testing.T
 }
 
+// setup connects to the DynamoDB Local given by DDB_ENDPOINT, such as http://localhost:8000, and recreates the
+// tables in it.  The test is skipped when DDB_ENDPOINT is not set.
 func setup(t *testing.T) *env {
-	db := getDynamodbConnection(t)
+	endpoint := os.Getenv("DDB_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("DDB_ENDPOINT is not set")
+	}
+	db := getDynamodbConnection(t, endpoint)
 	teardownTables(t, db)
 	setupTables(t, db)
 	return &env{
@@ -30,9 +38,9 @@ func setupTables(t *testing.T, db *dynamodb.DynamoDB) {
 	createNodeTable(t, db)
 }
 
-func getDynamodbConnection(t *testing.T) *dynamodb.DynamoDB {
-	db := dynamodb.New(&aws.Config{
-		Endpoint:   LOCAL_ENDPOINT,
+func getDynamodbConnection(t *testing.T, endpoint string) *dynamodb.DynamoDB {
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:   aws.String(endpoint),
 		Region:     LOCAL_REGION,
 		MaxRetries: LOCAL_MAX_RETRIES,
 		Credentials: credentials.NewStaticCredentials(
@@ -44,6 +52,10 @@ func getDynamodbConnection(t *testing.T) *dynamodb.DynamoDB {
 			Timeout: time.Duration(LOCAL_TIMEOUT) * time.Second,
 		},
 	})
+	if err != nil {
+		t.Fatalf("dynamodb session: %s", err.Error())
+	}
+	db := dynamodb.New(sess)
 
 	resp, err := db.ListTables(&dynamodb.ListTablesInput{})
 	if err != nil {
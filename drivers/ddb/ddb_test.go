@@ -1,6 +1,7 @@
 package ddb
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"math/rand"
@@ -15,7 +16,6 @@ import (
 
 var (
 	// Parameters for DDB Local
-	LOCAL_ENDPOINT      = aws.String("http://localhost:8000")
 	LOCAL_REGION        = aws.String("us-west-2")
 	LOCAL_MAX_RETRIES   = aws.Int(1)
 	LOCAL_KEY           = "key"
@@ -33,6 +33,17 @@ func newSid() string {
 	return hex.EncodeToString(a[:3])
 }
 
+// stored returns the properties of a node put by addNodesToDB as GetNodes reads them back
+func stored(sid, nid string) *backend.Properties {
+	properties := &backend.Properties{}
+	properties.SetString(*NODE_HASH, fmt.Sprintf("%s:%s", sid, nid))
+	properties.SetString(*NODE_RANGE, nid)
+	properties.SetString("string", "test")
+	properties.SetNumber("number", backend.MustParseNumber("0"))
+	properties.SetNumber("bool", backend.MustParseNumber("1"))
+	return properties
+}
+
 func Test_GetNodes(t *testing.T) {
 
 	env := setup(t)
@@ -52,19 +63,13 @@ func Test_GetNodes(t *testing.T) {
 	var sid5 = newSid()
 
 	nodes150 := make([][]string, 0, 150)
-	input150 := &backend.Nodes{SOURCE_ID: &backend.Properties{"nid": sid3}}
-	output150 := &backend.Nodes{SOURCE_ID: &backend.Properties{"nid": sid3}}
+	input150 := &backend.Nodes{}
+	output150 := &backend.Nodes{}
 	for i := 0; i < 150; i++ {
 		nid := fmt.Sprintf("%d", i+1)
 		nodes150 = append(nodes150, []string{sid3, nid})
 		(*input150)[nid] = &backend.Properties{}
-		(*output150)[nid] = &backend.Properties{
-			"sid_nid": fmt.Sprintf("%s:%s", sid3, nid),
-			"nid":     nid,
-			"string":  "test",
-			"number":  "0",
-			"bool":    "1",
-		}
+		(*output150)[nid] = stored(sid3, nid)
 
 	}
 
@@ -74,18 +79,10 @@ func Test_GetNodes(t *testing.T) {
 				{sid, "1"}, {sid, "2"}, {sid, "3"},
 			},
 			input: &backend.Nodes{
-				SOURCE_ID: &backend.Properties{"nid": sid},
-				"1":       &backend.Properties{},
+				"1": &backend.Properties{},
 			},
 			output: &backend.Nodes{
-				SOURCE_ID: &backend.Properties{"nid": sid},
-				"1": &backend.Properties{
-					"sid_nid": fmt.Sprintf("%s:%s", sid, "1"),
-					"nid":     "1",
-					"string":  "test",
-					"number":  "0",
-					"bool":    "1",
-				},
+				"1": stored(sid, "1"),
 			},
 			driver: &Driver{
 				Connection:    env.db,
@@ -99,26 +96,12 @@ func Test_GetNodes(t *testing.T) {
 				{sid2, "1"}, {sid2, "2"}, {sid2, "3"},
 			},
 			input: &backend.Nodes{
-				SOURCE_ID: &backend.Properties{"nid": sid2},
-				"1":       &backend.Properties{},
-				"2":       &backend.Properties{},
+				"1": &backend.Properties{},
+				"2": &backend.Properties{},
 			},
 			output: &backend.Nodes{
-				SOURCE_ID: &backend.Properties{"nid": sid2},
-				"1": &backend.Properties{
-					"sid_nid": fmt.Sprintf("%s:%s", sid2, "1"),
-					"nid":     "1",
-					"string":  "test",
-					"number":  "0",
-					"bool":    "1",
-				},
-				"2": &backend.Properties{
-					"sid_nid": fmt.Sprintf("%s:%s", sid2, "2"),
-					"nid":     "2",
-					"string":  "test",
-					"number":  "0",
-					"bool":    "1",
-				},
+				"1": stored(sid2, "1"),
+				"2": stored(sid2, "2"),
 			},
 			driver: &Driver{
 				Connection:    env.db,
@@ -143,12 +126,10 @@ func Test_GetNodes(t *testing.T) {
 				{sid4, "1"}, {sid4, "2"}, {sid4, "3"},
 			},
 			input: &backend.Nodes{
-				SOURCE_ID: &backend.Properties{"nid": sid4},
-				"4":       &backend.Properties{},
+				"4": &backend.Properties{},
 			},
 			output: &backend.Nodes{
-				SOURCE_ID: &backend.Properties{"nid": sid4},
-				"4":       &backend.Properties{},
+				"4": &backend.Properties{},
 			},
 			driver: &Driver{
 				Connection:    env.db,
@@ -162,19 +143,11 @@ func Test_GetNodes(t *testing.T) {
 				{sid5, "1"}, {sid5, "2"}, {sid5, "3"},
 			},
 			input: &backend.Nodes{
-				SOURCE_ID: &backend.Properties{"nid": sid5},
-				"1":       &backend.Properties{},
-				"4":       &backend.Properties{},
+				"1": &backend.Properties{},
+				"4": &backend.Properties{},
 			},
 			output: &backend.Nodes{
-				SOURCE_ID: &backend.Properties{"nid": sid5},
-				"1": &backend.Properties{
-					"sid_nid": fmt.Sprintf("%s:%s", sid5, "1"),
-					"nid":     "1",
-					"string":  "test",
-					"number":  "0",
-					"bool":    "1",
-				},
+				"1": stored(sid5, "1"),
 				"4": &backend.Properties{},
 			},
 			driver: &Driver{
@@ -189,9 +162,9 @@ func Test_GetNodes(t *testing.T) {
 	for testDescription, testCase := range tests {
 		env.addNodesToDB(testCase.nodesToAdd)
 
-		resp, err := testCase.driver.GetNodes(testCase.input)
-		if !reflect.DeepEqual(resp, testCase.output) {
-			t.Errorf("%s %s\n", testDescription, spew.Sprintf("expected\n%#+v\ngot\n%#+v", testCase.output, resp))
+		_, err := testCase.driver.GetNodes(context.Background(), testCase.input)
+		if !reflect.DeepEqual(testCase.input, testCase.output) {
+			t.Errorf("%s %s\n", testDescription, spew.Sprintf("expected\n%#+v\ngot\n%#+v", testCase.output, testCase.input))
 		}
 		if err != testCase.err {
 			t.Errorf("%s error missmatch: %s\n", testDescription, err.Error())
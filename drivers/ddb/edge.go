@@ -1,162 +1,319 @@
 package ddb
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"strings"
+	"sort"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/sir-wiggles/bcfs/backend"
 )
 
-func (d *Driver) GetInEdges(edges *backend.Edges) error {
-	var err error
+// GetInEdges will get the edges pointing to a node, keyed by the to nid and then the from nid.  When from nids
+// are given only those edges are fetched, otherwise every edge coming into the node is, and edges that are asked
+// for but not found are reported as backend.ErrNotFound.  The queries and batches are sent in parallel.
+func (d *Driver) GetInEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+
 	var sid = d.SourceID
-	items := make([]map[string]*dynamodb.AttributeValue, 0, len(*edges))
-	for id, tos := range *edges {
-		hash := aws.String(fmt.Sprintf("%s:%s", sid, id))
-		key := &dynamodb.QueryInput{
-			TableName: aws.String(d.EdgeTableName),
-			IndexName: EDGE_GSI_REVERSE,
-			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-				*EDGE_RANGE: aws.String(hash),
-			},
-			KeyConditionExpression: aws.String(fmt.Sprintf("%s = %s", *EDGE_RANGE, id)),
-		}
-		resp := d.query(key)
-		items = append(items, resp...)
+	results := make(backend.EdgeResults, len(*edges))
+	tids := make([]string, 0, len(*edges))
+	for tid := range *edges {
+		tids = append(tids, tid)
 	}
-	for _, item := range items {
-		sid_fid := *item[*EDGE_HASH].S
-		sid_tid := *item[*EDGE_RANGE].S
-		fid := strings.Split(sid_fid, ":")[1]
-		tid := strings.Split(sid_tid, ":")[1]
-		edge := edges.GetEdgeByID(tid, fid)
-		for key, value := range item {
-			field := getFieldOfInterest(value)
-			switch field {
-			case "S":
-				edge.SetString(key, *value.S)
-			case "B":
-				edge.SetBinary(key, value.B)
-			case "N":
-				edge.SetNumber(key, *value.N)
-			case "BOOL", "BS", "L", "M", "NS", "NULL", "SS":
-				return fmt.Errorf("dynamodb type %s is not implemented", field)
-			case "":
-				return fmt.Errorf("no field found for %s", edge)
-			}
+	sort.Strings(tids)
+
+	fetches := make([]fetch, 0, len(tids))
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(tids))
+	for _, tid := range tids {
+		froms := (*edges)[tid]
+		if len(froms) == 0 {
+			fetches = append(fetches, d.queryFetch(&dynamodb.QueryInput{
+				TableName: aws.String(d.EdgeTableName),
+				IndexName: EDGE_GSI_REVERSE,
+				ExpressionAttributeNames: map[string]*string{
+					"#to": EDGE_RANGE,
+				},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":to": &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("%s:%s", sid, tid))},
+				},
+				KeyConditionExpression: aws.String("#to = :to"),
+			}))
+			continue
+		}
+		fids := make([]string, 0, len(froms))
+		for fid := range froms {
+			fids = append(fids, fid)
+		}
+		sort.Strings(fids)
+		for _, fid := range fids {
+			results.Set(tid, fid, backend.ErrNotFound)
+			keys = append(keys, d.edgeKey(fid, tid))
 		}
 	}
-	return nil
+	items, err := d.fanOut(ctx, append(fetches, d.batchGets(d.EdgeTableName, keys)...))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		fid, tid := edgeIDs(item)
+		results.Set(tid, fid, unmarshalItem(item, edges.GetEdgeByID(tid, fid)))
+	}
+	return results, nil
 }
 
-func (d *Driver) query(key *dynamodb.QueryInput) []map[string]*dynamodb.AttributeValue {
-	items := make([]map[string]*dynamodb.AttributeValue, 100)
+// query gets every page of the query.  A page that fails for a reason that may go away is asked for again,
+// backing off between attempts, until the retry budget runs out.  The pages are asked for with a copy of the
+// input so it is left as it was and can be shared by queries running at the same time.
+func (d *Driver) query(ctx context.Context, query *dynamodb.QueryInput) ([]map[string]*dynamodb.AttributeValue, error) {
+	input := *query
+	items := make([]map[string]*dynamodb.AttributeValue, 0, 100)
+	b := d.backoff("Query")
 	for {
-		resp, err := d.Connection.Query(key)
-		if err != nil {
-			log.Println(err.Error())
+		req, resp := d.Connection.QueryRequest(&input)
+		if err := d.send(ctx, req); err != nil {
+			if !retryable(err) {
+				return nil, err
+			}
+			if err := b.wait(ctx, err, 0); err != nil {
+				return nil, err
+			}
 			continue
 		}
 		items = append(items, resp.Items...)
 		if resp.LastEvaluatedKey == nil {
 			break
 		}
-		key.ExclusiveStartKey = resp.LastEvaluatedKey
+		input.ExclusiveStartKey = resp.LastEvaluatedKey
 	}
-	return resp.Items
+	return items, nil
 }
 
-// GetOutEdges will get all the edges extending from a parent node and going to its children.
-// This will utilize batch as much as possible
-func (d *Driver) GetOutEdges(edges *backend.Edges) error {
+// GetOutEdges will get all the edges extending from a parent node and going to its children, keyed by the from
+// nid and then the to nid.  When to nids are given only those edges are fetched, otherwise every edge going out
+// of the parent is and edges that are asked for but not found are reported as backend.ErrNotFound.  This will
+// utilize batch as much as possible, and the queries and batches are sent in parallel.
+func (d *Driver) GetOutEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
 
-	var err error
 	var sid = d.SourceID
-	keys := make([]map[string]*dynamodb.AttributeValue, 0, 100)
-	items := make([]map[string]*dynamodb.AttributeValue, 0, len(*edges))
-	subSet := make([]map[string]*dynamodb.AttributeValue, 0, 100)
-	for fid, tos := range *edges {
-		hash := aws.String(fmt.Sprintf("%s:%s", sid, fid))
-		for tid, _ := range tos {
-			rang := aws.String(fmt.Sprintf("%s:%s", sid, tid))
-			key := map[string]*dynamodb.AttributeValue{
-				*EDGE_HASH: &dynamodb.AttributeValue{S: hash},
-			}
-			keys = append(keys, key)
-			if len(keys) == 100 {
-				subSet, err = d.batchGet(d.EdgeTableName, keys)
-				if err != nil {
-					return err
-				}
-				keys = make([]map[string]*dynamodb.AttributeValue, 0, 100)
-				items = append(items, subSet...)
-			}
-		}
+	results := make(backend.EdgeResults, len(*edges))
+	fids := make([]string, 0, len(*edges))
+	for fid := range *edges {
+		fids = append(fids, fid)
 	}
-	if len(keys) > 0 {
-		subSet, err = d.batchGet(d.EdgeTableName, keys)
+	sort.Strings(fids)
+
+	fetches := make([]fetch, 0, len(fids))
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(fids))
+	for _, fid := range fids {
+		tos := (*edges)[fid]
+		if len(tos) == 0 {
+			fetches = append(fetches, d.queryFetch(&dynamodb.QueryInput{
+				TableName: aws.String(d.EdgeTableName),
+				ExpressionAttributeNames: map[string]*string{
+					"#from": EDGE_HASH,
+					"#to":   EDGE_RANGE,
+				},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":from": &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("%s:%s", sid, fid))},
+					":to":   &dynamodb.AttributeValue{S: aws.String(sid + ":")},
+				},
+				// leave out the items locking the edge names, see nameKey
+				KeyConditionExpression: aws.String("#from = :from AND begins_with(#to, :to)"),
+			}))
+			continue
+		}
+		tids := make([]string, 0, len(tos))
+		for tid := range tos {
+			tids = append(tids, tid)
+		}
+		sort.Strings(tids)
+		for _, tid := range tids {
+			results.Set(fid, tid, backend.ErrNotFound)
+			keys = append(keys, d.edgeKey(fid, tid))
+		}
 	}
+	items, err := d.fanOut(ctx, append(fetches, d.batchGets(d.EdgeTableName, keys)...))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	items = append(items, subSet...)
 
 	for _, item := range items {
-		sid_fid := *item["sid_from"].S
-		sid_tid := *item["sid_to"].S
-		fid := strings.Split(sid_fid, ":")[1]
-		tid := strings.Split(sid_tid, ":")[1]
-		edge := edges.GetEdgeByID(fid, tid)
-		for key, value := range item {
-			field := getFieldOfInterest(value)
-			switch field {
-			case "S":
-				edge.SetString(key, *value.S)
-			case "B":
-				edge.SetBinary(key, value.B)
-			case "N":
-				edge.SetNumber(key, *value.N)
-			case "BOOL", "BS", "L", "M", "NS", "NULL", "SS":
-				return fmt.Errorf("dynamodb type %s is not implemented", field)
-			case "":
-				return fmt.Errorf("no field found for %s", edge)
+		fid, tid := edgeIDs(item)
+		results.Set(fid, tid, unmarshalItem(item, edges.GetEdgeByID(fid, tid)))
+	}
+	return results, nil
+}
+
+// CreateEdges puts the edges into the edge table.  An edge that already exists between the same two nids, or
+// whose name is already taken by another edge out of the same nid, is left unchanged and reported as
+// backend.ErrAlreadyExists, the same as neo does.  A named edge is put in the same transaction as the item
+// locking its name, see nameKey.  The puts are sent in parallel, so when several edges out of the same nid are
+// given the same name only the one with the smallest to nid is put and the others are reported as
+// backend.ErrAlreadyExists rather than racing for the lock.
+func (d *Driver) CreateEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+
+	results := make(backend.EdgeResults, len(*edges))
+	pairs := make([][2]string, 0, len(*edges))
+	items := make([][]*dynamodb.TransactWriteItem, 0, len(*edges))
+	writes := make([][]txWrite, 0, len(*edges))
+	for fid, tos := range *edges {
+		tids := make([]string, 0, len(tos))
+		for tid := range tos {
+			tids = append(tids, tid)
+		}
+		sort.Strings(tids)
+
+		claimed := make(map[string]bool)
+		for _, tid := range tids {
+			properties := tos[tid]
+			item, err := marshalProperties(properties)
+			var name string
+			if err == nil {
+				name, _, err = edgeName(properties)
+			}
+			if err == nil && name != "" && claimed[name] {
+				err = backend.ErrAlreadyExists
 			}
+			if err != nil {
+				results.Set(fid, tid, err)
+				continue
+			}
+			claimed[name] = true
+			for key, value := range d.edgeKey(fid, tid) {
+				item[key] = value
+			}
+			i, w := d.createEdgeItems(fid, tid, item, name)
+			pairs, items, writes = append(pairs, [2]string{fid, tid}), append(items, i), append(writes, w)
 		}
 	}
-	return nil
+	return d.edgeWrites(ctx, results, pairs, items, writes)
 }
-func (d *Driver) CreateEdges(edges *backend.Edges) error {
-
-	items := make([]*dynamodb.WriteRequest, 0, 25)
-	groups := make([]map[string][]*dynamodb.BatchWriteItemInput, 0, 1)
-	for nid, properties := range *edges {
-
-		item := make(map[string]*dynamodb.AttributeValue, 0, len(properties))
-		for key, property := range properties {
-			switch property.Type {
-			case backend.StringProperty:
-				value := property.Value.(string)
-				item[key] = &dynamodb.AttributeValue{S: aws.String(value)}
-			case backend.NumberProperty:
-				value := property.Value.(int)
-				item[key] = &dynamodb.AttributeValue{N: aws.String(value)}
-			case backend.BinaryProperty:
-				value := property.Value.(int)
-				item[key] = &dynamodb.AttributeValue{N: aws.String(value)}
+
+// AlterEdges sets the given properties on existing edges.  Edges that do not exist are left alone rather than
+// being created, the same as neo does for a MATCH that finds nothing, and are reported as backend.ErrNotFound.
+// A change of name moves the item locking it in the same transaction as the update, and is reported as
+// backend.ErrAlreadyExists if another edge out of the same nid has the new name or backend.ErrConflict if the
+// edge was renamed or deleted since its name was read.
+func (d *Driver) AlterEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+
+	results := make(backend.EdgeResults, len(*edges))
+	unchanged := make(backend.Edges)
+	for fid, tos := range *edges {
+		for tid, properties := range tos {
+			expression, names, values, err := setExpression(properties, *EDGE_HASH, *EDGE_RANGE)
+			if err != nil {
+				results.Set(fid, tid, err)
+				continue
+			}
+			if expression == nil {
+				// nothing to change, only check the edge exists
+				unchanged.GetEdgeByID(fid, tid)
+				continue
+			}
+			err = d.alterEdge(ctx, fid, tid, properties, expression, names, values)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			results.Set(fid, tid, err)
+		}
+	}
+
+	if len(unchanged) > 0 {
+		found, err := d.GetOutEdges(ctx, &unchanged)
+		if err != nil {
+			return nil, err
+		}
+		for fid, tos := range found {
+			for tid, err := range tos {
+				results.Set(fid, tid, err)
 			}
 		}
-		items = append(items, dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}})
-		if len(items) == 25 {
-			groups = append(groups, map[string]*dynamodb.BatchWriteItemInput{d.NodeTableName: items})
-			items = make([]*dynamodb.WriteRequest, 0, 25)
+	}
+	return results, nil
+}
+
+// alterEdge sends the update of the edge from fid to tid setting the properties
+func (d *Driver) alterEdge(ctx context.Context, fid, tid string, properties *backend.Properties, expression *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) error {
+
+	name, renamed, err := edgeName(properties)
+	if err != nil {
+		return err
+	}
+	if renamed {
+		old, found, err := d.readName(ctx, fid, tid)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return backend.ErrNotFound
+		}
+		if old != name {
+			items, writes := d.renameEdgeItems(fid, tid, old, name, expression, names, values)
+			return d.transact(ctx, items, writes)
 		}
 	}
-	if len(items) > 0 {
-		groups = append(groups, items)
+
+	err = d.retry(ctx, "UpdateItem", func() error {
+		req, _ := d.Connection.UpdateItemRequest(&dynamodb.UpdateItemInput{
+			TableName:                 aws.String(d.EdgeTableName),
+			Key:                       d.edgeKey(fid, tid),
+			UpdateExpression:          expression,
+			ConditionExpression:       aws.String(fmt.Sprintf("attribute_exists(%s)", *EDGE_HASH)),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		})
+		return d.send(ctx, req)
+	})
+	if conditionFailed(err) {
+		return backend.ErrNotFound
 	}
-	err := d.batchWrite(groups)
 	return err
 }
+
+// DeleteEdges removes the edges between the given from and to nids along with the items locking their names.
+// Deleting an edge that does not exist is not an error, and an edge renamed since its name was read is left
+// alone and reported as backend.ErrConflict.  The deletes are sent in parallel.
+func (d *Driver) DeleteEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+
+	results := make(backend.EdgeResults, len(*edges))
+	pairs := make([][2]string, 0, len(*edges))
+	for fid, tos := range *edges {
+		for tid := range tos {
+			pairs = append(pairs, [2]string{fid, tid})
+		}
+	}
+
+	errs, err := d.forEach(ctx, len(pairs), func(ctx context.Context, i int) error {
+		fid, tid := pairs[i][0], pairs[i][1]
+		name, found, err := d.readName(ctx, fid, tid)
+		if err != nil || !found {
+			return err
+		}
+		items, writes := d.deleteEdgeItems(fid, tid, name)
+		return d.transact(ctx, items, writes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, pair := range pairs {
+		results.Set(pair[0], pair[1], errs[i])
+	}
+	return results, nil
+}
+
+// edgeWrites sends the writes of each edge in parallel and records the outcome against the edge at the same
+// index
+func (d *Driver) edgeWrites(ctx context.Context, results backend.EdgeResults, pairs [][2]string, items [][]*dynamodb.TransactWriteItem, writes [][]txWrite) (backend.EdgeResults, error) {
+	errs, err := d.forEach(ctx, len(pairs), func(ctx context.Context, i int) error {
+		return d.transact(ctx, items[i], writes[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, pair := range pairs {
+		results.Set(pair[0], pair[1], errs[i])
+	}
+	return results, nil
+}
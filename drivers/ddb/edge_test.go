@@ -0,0 +1,55 @@
+package ddb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// connection returns a client whose requests never leave the process, send standing in for dynamodb by filling
+// in the output of each request
+func connection(send func(r *request.Request)) *dynamodb.DynamoDB {
+	c := dynamodb.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("local"),
+		Credentials: credentials.NewStaticCredentials("key", "secret", ""),
+	})))
+	c.Handlers.Clear()
+	c.Handlers.Send.PushBack(send)
+	return c
+}
+
+func Test_query(t *testing.T) {
+	d := &Driver{SourceID: "query-test", Connection: connection(func(r *request.Request) {
+		input, output := r.Params.(*dynamodb.QueryInput), r.Data.(*dynamodb.QueryOutput)
+		if input.ExclusiveStartKey == nil {
+			output.Items = []map[string]*dynamodb.AttributeValue{item(1)}
+			output.LastEvaluatedKey = item(1)
+			return
+		}
+		output.Items = []map[string]*dynamodb.AttributeValue{item(2)}
+	})}
+
+	// + test
+	input := &dynamodb.QueryInput{TableName: aws.String("edges")}
+	items, err := d.query(context.Background(), input)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(items) != 2 {
+		t.Errorf("expected both pages got %d items", len(items))
+	}
+
+	// - test
+	// the input is left as it was so it can be used again
+	if input.ExclusiveStartKey != nil || input.ReturnConsumedCapacity != nil {
+		t.Errorf("expected the input unchanged got %v", input)
+	}
+	if items, _ := d.query(context.Background(), input); len(items) != 2 {
+		t.Errorf("expected both pages again got %d items", len(items))
+	}
+}
@@ -0,0 +1,156 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// newError maps an error returned from dynamodb for the operation op into a backend error.  Context errors and
+// errors that are already backend errors are returned as is.
+func newError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var berr *backend.Error
+	if errors.As(err, &berr) {
+		return err
+	}
+	return backend.NewError(PACKAGE_NAME, op, errorKind(err), err)
+}
+
+// errorKind classifies an aws error into one of the backend error kinds, or nil if it is none of them
+func errorKind(err error) error {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return nil
+	}
+	switch aerr.Code() {
+	case dynamodb.ErrCodeConditionalCheckFailedException,
+		dynamodb.ErrCodeTransactionConflictException,
+		dynamodb.ErrCodeTransactionCanceledException,
+		dynamodb.ErrCodeTransactionInProgressException:
+		return backend.ErrConflict
+	case dynamodb.ErrCodeResourceNotFoundException:
+		return backend.ErrNotFound
+	case dynamodb.ErrCodeResourceInUseException:
+		return backend.ErrAlreadyExists
+	case dynamodb.ErrCodeProvisionedThroughputExceededException,
+		dynamodb.ErrCodeRequestLimitExceeded,
+		"ThrottlingException":
+		return backend.ErrThrottled
+	case dynamodb.ErrCodeInternalServerError,
+		"ServiceUnavailable",
+		request.ErrCodeRequestError,
+		request.ErrCodeResponseTimeout:
+		return backend.ErrUnavailable
+	}
+	var rerr awserr.RequestFailure
+	if errors.As(err, &rerr) && rerr.StatusCode() >= 500 {
+		return backend.ErrUnavailable
+	}
+	return nil
+}
+
+// conditionFailed reports whether err is dynamodb refusing a write because its condition did not hold
+func conditionFailed(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
+
+// CancelledError is returned when dynamodb cancels a transaction.  It says which of the writes and checks of the
+// transaction were the reason, and comes wrapped in a backend.Error of kind backend.ErrAlreadyExists when a
+// create found its item already there, or backend.ErrConflict when another condition failed or the transaction
+// clashed with another one.
+type CancelledError struct {
+	Reasons []CancelReason
+}
+
+// CancelReason is why a single write or check cancelled a transaction
+type CancelReason struct {
+	// Write describes the write or check, e.g. "put node 1"
+	Write   string
+	Code    string
+	Message string
+	// kind is what the write reports a failed condition as
+	kind error
+}
+
+func (e *CancelledError) Error() string {
+	reasons := make([]string, 0, len(e.Reasons))
+	for _, reason := range e.Reasons {
+		if reason.Message == "" {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", reason.Write, reason.Code))
+			continue
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %s: %s", reason.Write, reason.Code, reason.Message))
+	}
+	return fmt.Sprintf("transaction cancelled by %s", strings.Join(reasons, ", "))
+}
+
+// txWrite describes an item of a transaction for the reasons of a CancelledError
+type txWrite struct {
+	description string
+	// failed is the kind of error a failed condition of the item is reported as
+	failed error
+}
+
+// cancelled maps a TransactionCanceledException for the items described by writes into a CancelledError.  The
+// cancellation reasons come in the same order as the items, with a code of None for those that were fine.  Any
+// other error is returned as is.
+func cancelled(err error, writes []txWrite) error {
+	var cerr *dynamodb.TransactionCanceledException
+	if !errors.As(err, &cerr) {
+		return err
+	}
+	result := &CancelledError{}
+	for i, reason := range cerr.CancellationReasons {
+		code := aws.StringValue(reason.Code)
+		if code == "" || code == "None" {
+			continue
+		}
+		write := txWrite{description: fmt.Sprintf("write %d", i), failed: backend.ErrConflict}
+		if i < len(writes) {
+			write = writes[i]
+		}
+		result.Reasons = append(result.Reasons, CancelReason{
+			Write: write.description, Code: code, Message: aws.StringValue(reason.Message), kind: write.failed,
+		})
+	}
+	return backend.NewError(PACKAGE_NAME, "TransactWriteItems", cancelKind(result.Reasons), result)
+}
+
+// cancelKind classifies the reasons a transaction was cancelled, looking at all of them since they come in the
+// order of the items rather than by importance.  A failed condition is reported as the kind of the first write
+// it belongs to and wins over a conflict with another transaction, which wins over throttling, since trying
+// again would not help with a failed condition and may not with a conflict.
+func cancelKind(reasons []CancelReason) error {
+	var conflict, throttled bool
+	for _, reason := range reasons {
+		switch reason.Code {
+		case "ConditionalCheckFailed":
+			return reason.kind
+		case "TransactionConflict":
+			conflict = true
+		case "ThrottlingError", "ProvisionedThroughputExceeded", "RequestLimitExceeded":
+			throttled = true
+		}
+	}
+	switch {
+	case conflict:
+		return backend.ErrConflict
+	case throttled:
+		return backend.ErrThrottled
+	}
+	return nil
+}
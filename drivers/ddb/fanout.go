@@ -0,0 +1,138 @@
+package ddb
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// DEFAULT_CONCURRENCY is how many requests a single call sends at once when the config leaves it out
+var DEFAULT_CONCURRENCY = 8
+
+// MAX_BATCH_GET_KEYS is the most keys dynamodb accepts in a single BatchGetItem call
+var MAX_BATCH_GET_KEYS = 100
+
+// fetch is a single request of a fan out, returning the items it read
+type fetch func(ctx context.Context) ([]map[string]*dynamodb.AttributeValue, error)
+
+// fanOut runs the fetches with at most the concurrency of the driver running at once and returns their items in
+// the order of the fetches, so the result does not depend on which request came back first.  The first fetch to
+// fail cancels the context of the others, no more are started, and its error is returned once they have all
+// stopped.
+func (d *Driver) fanOut(ctx context.Context, fetches []fetch) ([]map[string]*dynamodb.AttributeValue, error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	parts := make([][]map[string]*dynamodb.AttributeValue, len(fetches))
+	limit := make(chan struct{}, d.concurrencyOrDefault())
+	for i, f := range fetches {
+		select {
+		case limit <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, f fetch) {
+			defer wg.Done()
+			defer func() { <-limit }()
+			items, err := f(ctx)
+			if err != nil {
+				once.Do(func() {
+					first = err
+					cancel()
+				})
+				return
+			}
+			parts[i] = items
+		}(i, f)
+	}
+	wg.Wait()
+
+	if first != nil {
+		return nil, first
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	size := 0
+	for _, part := range parts {
+		size += len(part)
+	}
+	items := make([]map[string]*dynamodb.AttributeValue, 0, size)
+	for _, part := range parts {
+		items = append(items, part...)
+	}
+	return items, nil
+}
+
+// forEach calls fn for every index up to n with at most the concurrency of the driver running at once and
+// returns the error of each call at its index.  Unlike fanOut a failed call does not stop the others, since each
+// is the write of a separate item.  An error is only returned when the context is done, and no more calls are
+// started once it is.
+func (d *Driver) forEach(ctx context.Context, n int, fn func(ctx context.Context, i int) error) ([]error, error) {
+
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	limit := make(chan struct{}, d.concurrencyOrDefault())
+	for i := 0; i < n; i++ {
+		select {
+		case limit <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-limit }()
+			errs[i] = fn(ctx, i)
+		}(i)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+// concurrencyOrDefault returns the concurrency of the driver, or the default for a driver built without one
+func (d *Driver) concurrencyOrDefault() int {
+	if d.concurrency < 1 {
+		return DEFAULT_CONCURRENCY
+	}
+	return d.concurrency
+}
+
+// batchGets returns a fetch for every chunk of keys that fits in a single BatchGetItem call
+func (d *Driver) batchGets(table string, keys []map[string]*dynamodb.AttributeValue) []fetch {
+	fetches := make([]fetch, 0, (len(keys)+MAX_BATCH_GET_KEYS-1)/MAX_BATCH_GET_KEYS)
+	for start := 0; start < len(keys); start += MAX_BATCH_GET_KEYS {
+		end := start + MAX_BATCH_GET_KEYS
+		if end > len(keys) {
+			end = len(keys)
+		}
+		chunk := keys[start:end]
+		fetches = append(fetches, func(ctx context.Context) ([]map[string]*dynamodb.AttributeValue, error) {
+			return d.batchGet(ctx, table, chunk)
+		})
+	}
+	return fetches
+}
+
+// queryFetch returns a fetch running the query
+func (d *Driver) queryFetch(input *dynamodb.QueryInput) fetch {
+	return func(ctx context.Context) ([]map[string]*dynamodb.AttributeValue, error) {
+		return d.query(ctx, input)
+	}
+}
@@ -0,0 +1,128 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// item returns an item holding only the given nid
+func item(nid int) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{*NODE_RANGE: &dynamodb.AttributeValue{S: aws.String(strconv.Itoa(nid))}}
+}
+
+func Test_fanOut(t *testing.T) {
+	d := &Driver{concurrency: 3}
+
+	// + test
+	// the items come back in the order of the fetches, however long each one takes
+	var running, most int32
+	fetches := make([]fetch, 10)
+	for i := range fetches {
+		i := i
+		fetches[i] = func(ctx context.Context) ([]map[string]*dynamodb.AttributeValue, error) {
+			now := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				seen := atomic.LoadInt32(&most)
+				if now <= seen || atomic.CompareAndSwapInt32(&most, seen, now) {
+					break
+				}
+			}
+			time.Sleep(time.Duration(10-i) * time.Millisecond)
+			return []map[string]*dynamodb.AttributeValue{item(i)}, nil
+		}
+	}
+	items, err := d.fanOut(context.Background(), fetches)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for i, it := range items {
+		if *it[*NODE_RANGE].S != strconv.Itoa(i) {
+			t.Fatalf("expected item %d got %s", i, *it[*NODE_RANGE].S)
+		}
+	}
+	if len(items) != 10 || most > 3 {
+		t.Errorf("expected 10 items with at most 3 fetches at once got %d items with %d", len(items), most)
+	}
+
+	// - test
+	// the first failure stops the others and no more are started
+	failed := errors.New("failed")
+	var started int32
+	fetches = make([]fetch, 20)
+	for i := range fetches {
+		i := i
+		fetches[i] = func(ctx context.Context) ([]map[string]*dynamodb.AttributeValue, error) {
+			atomic.AddInt32(&started, 1)
+			if i == 0 {
+				return nil, failed
+			}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+	}
+	if _, err := d.fanOut(context.Background(), fetches); err != failed {
+		t.Errorf("expected the first failure got %v", err)
+	}
+	if started > 3 {
+		t.Errorf("expected no fetches started after the failure got %d", started)
+	}
+}
+
+func Test_forEach(t *testing.T) {
+	d := &Driver{concurrency: 3}
+	failed := errors.New("failed")
+
+	// + test
+	// a failed call does not stop the others and each error is at its index
+	var running, most int32
+	errs, err := d.forEach(context.Background(), 10, func(ctx context.Context, i int) error {
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			seen := atomic.LoadInt32(&most)
+			if now <= seen || atomic.CompareAndSwapInt32(&most, seen, now) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		if i%2 == 0 {
+			return failed
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for i, err := range errs {
+		if (i%2 == 0) != (err == failed) {
+			t.Errorf("call %d: unexpected error %v", i, err)
+		}
+	}
+	if len(errs) != 10 || most > 3 {
+		t.Errorf("expected 10 errors with at most 3 calls at once got %d errors with %d", len(errs), most)
+	}
+
+	// - test
+	// a done context stops the calls
+	ctx, cancel := context.WithCancel(context.Background())
+	var started int32
+	if _, err := d.forEach(ctx, 20, func(ctx context.Context, i int) error {
+		if atomic.AddInt32(&started, 1) == 1 {
+			cancel()
+		}
+		return nil
+	}); err != context.Canceled {
+		t.Errorf("expected the context error got %v", err)
+	}
+	if started == 20 {
+		t.Errorf("expected the calls to stop after the cancel")
+	}
+}
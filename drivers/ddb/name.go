@@ -0,0 +1,247 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// Every named edge has an item in the edge table locking its name under the from nid, written and removed in the
+// same transaction as the edge so no two edges out of a node ever hold the same name.  The lock has the from key
+// of the edge and a to key of the sid, EDGE_NAME_LOCK and the name, where an edge has the sid, a colon and the to
+// nid, so it is never read as an edge: the queries of the edges out of a node only take to keys starting with
+// the sid and a colon, and the lock has no name attribute to show up in the name-index LSI.
+
+// nameKey returns the key of the item locking the name to a single edge out of fid
+func (d *Driver) nameKey(fid, name string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		*EDGE_HASH:  &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("%s:%s", d.SourceID, fid))},
+		*EDGE_RANGE: &dynamodb.AttributeValue{S: aws.String(d.SourceID + EDGE_NAME_LOCK + name)},
+	}
+}
+
+// lockName returns the put of the item locking the name, on the condition that no other edge holds it
+func (d *Driver) lockName(fid, name string) (*dynamodb.TransactWriteItem, txWrite) {
+	return &dynamodb.TransactWriteItem{
+			Put: &dynamodb.Put{
+				TableName:           aws.String(d.EdgeTableName),
+				Item:                d.nameKey(fid, name),
+				ConditionExpression: aws.String(fmt.Sprintf("attribute_not_exists(%s)", *EDGE_HASH)),
+			},
+		},
+		txWrite{description: fmt.Sprintf("lock name %s %s", fid, name), failed: backend.ErrAlreadyExists}
+}
+
+// unlockName returns the delete of the item locking the name
+func (d *Driver) unlockName(fid, name string) (*dynamodb.TransactWriteItem, txWrite) {
+	return &dynamodb.TransactWriteItem{
+			Delete: &dynamodb.Delete{
+				TableName: aws.String(d.EdgeTableName),
+				Key:       d.nameKey(fid, name),
+			},
+		},
+		txWrite{description: fmt.Sprintf("unlock name %s %s", fid, name)}
+}
+
+// edgeName returns the name given by the properties of an edge and whether they set or remove it at all.  A
+// name given as nil removes it and comes back empty.
+func edgeName(properties *backend.Properties) (string, bool, error) {
+	property, ok := (*properties)[*EDGE_ATTR_NAME]
+	if !ok {
+		return "", false, nil
+	}
+	if property == nil {
+		return "", true, nil
+	}
+	name, err := properties.GetString(*EDGE_ATTR_NAME)
+	return name, true, err
+}
+
+// readName reads the name of the edge from fid to tid with a strongly consistent read.  found is false if there
+// is no such edge, and the name is empty if the edge has none.
+func (d *Driver) readName(ctx context.Context, fid, tid string) (name string, found bool, err error) {
+	var output *dynamodb.GetItemOutput
+	err = d.retry(ctx, "GetItem", func() error {
+		var req *request.Request
+		req, output = d.Connection.GetItemRequest(&dynamodb.GetItemInput{
+			TableName:            aws.String(d.EdgeTableName),
+			Key:                  d.edgeKey(fid, tid),
+			ConsistentRead:       aws.Bool(true),
+			ProjectionExpression: aws.String("#from, #name"),
+			ExpressionAttributeNames: map[string]*string{
+				"#from": EDGE_HASH,
+				"#name": EDGE_ATTR_NAME,
+			},
+		})
+		return d.send(ctx, req)
+	})
+	if err != nil || output.Item == nil {
+		return "", false, err
+	}
+	if value, ok := output.Item[*EDGE_ATTR_NAME]; ok {
+		return aws.StringValue(value.S), true, nil
+	}
+	return "", true, nil
+}
+
+// nameCondition returns the condition that the edge exists and still has the name it was read with, adding its
+// names and values to the given ones
+func nameCondition(name string, names map[string]*string, values map[string]*dynamodb.AttributeValue) *string {
+	names["#from"] = EDGE_HASH
+	names["#name"] = EDGE_ATTR_NAME
+	if name == "" {
+		return aws.String("attribute_exists(#from) AND attribute_not_exists(#name)")
+	}
+	values[":name"] = &dynamodb.AttributeValue{S: aws.String(name)}
+	return aws.String("attribute_exists(#from) AND #name = :name")
+}
+
+// createEdgeItems returns the writes creating the edge item from fid to tid, locking its name when it has one
+func (d *Driver) createEdgeItems(fid, tid string, item map[string]*dynamodb.AttributeValue, name string) ([]*dynamodb.TransactWriteItem, []txWrite) {
+	items := []*dynamodb.TransactWriteItem{{
+		Put: &dynamodb.Put{
+			TableName:           aws.String(d.EdgeTableName),
+			Item:                item,
+			ConditionExpression: aws.String(fmt.Sprintf("attribute_not_exists(%s)", *EDGE_HASH)),
+		},
+	}}
+	writes := []txWrite{{description: fmt.Sprintf("put edge %s %s", fid, tid), failed: backend.ErrAlreadyExists}}
+	if name != "" {
+		lock, write := d.lockName(fid, name)
+		items, writes = append(items, lock), append(writes, write)
+	}
+	return items, writes
+}
+
+// renameEdgeItems returns the writes of an update of the edge from fid to tid that changes its name from old to
+// name, moving the lock along with it.  The update only goes through if the edge still has the old name.
+func (d *Driver) renameEdgeItems(fid, tid, old, name string, expression *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) ([]*dynamodb.TransactWriteItem, []txWrite) {
+	if values == nil {
+		values = map[string]*dynamodb.AttributeValue{}
+	}
+	condition := nameCondition(old, names, values)
+	if len(values) == 0 {
+		values = nil
+	}
+	items := []*dynamodb.TransactWriteItem{{
+		Update: &dynamodb.Update{
+			TableName:                 aws.String(d.EdgeTableName),
+			Key:                       d.edgeKey(fid, tid),
+			UpdateExpression:          expression,
+			ConditionExpression:       condition,
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		},
+	}}
+	writes := []txWrite{{description: fmt.Sprintf("update edge %s %s", fid, tid), failed: backend.ErrConflict}}
+	if old != "" {
+		unlock, write := d.unlockName(fid, old)
+		items, writes = append(items, unlock), append(writes, write)
+	}
+	if name != "" {
+		lock, write := d.lockName(fid, name)
+		items, writes = append(items, lock), append(writes, write)
+	}
+	return items, writes
+}
+
+// deleteEdgeItems returns the writes deleting the edge from fid to tid along with the lock of its name.  The
+// delete only goes through if the edge still has the name it was read with.
+func (d *Driver) deleteEdgeItems(fid, tid, name string) ([]*dynamodb.TransactWriteItem, []txWrite) {
+	names := map[string]*string{}
+	values := map[string]*dynamodb.AttributeValue{}
+	condition := nameCondition(name, names, values)
+	if len(values) == 0 {
+		values = nil
+	}
+	items := []*dynamodb.TransactWriteItem{{
+		Delete: &dynamodb.Delete{
+			TableName:                 aws.String(d.EdgeTableName),
+			Key:                       d.edgeKey(fid, tid),
+			ConditionExpression:       condition,
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		},
+	}}
+	writes := []txWrite{{description: fmt.Sprintf("delete edge %s %s", fid, tid), failed: backend.ErrConflict}}
+	if name != "" {
+		unlock, write := d.unlockName(fid, name)
+		items, writes = append(items, unlock), append(writes, write)
+	}
+	return items, writes
+}
+
+// transact sends the writes in a single TransactWriteItems call, or as a plain conditional write when there is
+// only one of them since a transaction costs twice as much.  A failed condition comes back as the kind of the
+// write it belongs to.
+func (d *Driver) transact(ctx context.Context, items []*dynamodb.TransactWriteItem, writes []txWrite) error {
+	if len(items) == 1 {
+		return d.writeItem(ctx, items[0], writes[0])
+	}
+	return d.retry(ctx, "TransactWriteItems", func() error {
+		req, _ := d.Connection.TransactWriteItemsRequest(&dynamodb.TransactWriteItemsInput{
+			TransactItems: items,
+		})
+		return cancelled(d.send(ctx, req), writes)
+	})
+}
+
+// writeItem sends a single put, update or delete of a transaction on its own
+func (d *Driver) writeItem(ctx context.Context, item *dynamodb.TransactWriteItem, write txWrite) error {
+	var (
+		op    string
+		build func() *request.Request
+	)
+	switch {
+	case item.Put != nil:
+		op = "PutItem"
+		build = func() *request.Request {
+			req, _ := d.Connection.PutItemRequest(&dynamodb.PutItemInput{
+				TableName:                 item.Put.TableName,
+				Item:                      item.Put.Item,
+				ConditionExpression:       item.Put.ConditionExpression,
+				ExpressionAttributeNames:  item.Put.ExpressionAttributeNames,
+				ExpressionAttributeValues: item.Put.ExpressionAttributeValues,
+			})
+			return req
+		}
+	case item.Update != nil:
+		op = "UpdateItem"
+		build = func() *request.Request {
+			req, _ := d.Connection.UpdateItemRequest(&dynamodb.UpdateItemInput{
+				TableName:                 item.Update.TableName,
+				Key:                       item.Update.Key,
+				UpdateExpression:          item.Update.UpdateExpression,
+				ConditionExpression:       item.Update.ConditionExpression,
+				ExpressionAttributeNames:  item.Update.ExpressionAttributeNames,
+				ExpressionAttributeValues: item.Update.ExpressionAttributeValues,
+			})
+			return req
+		}
+	default:
+		op = "DeleteItem"
+		build = func() *request.Request {
+			req, _ := d.Connection.DeleteItemRequest(&dynamodb.DeleteItemInput{
+				TableName:                 item.Delete.TableName,
+				Key:                       item.Delete.Key,
+				ConditionExpression:       item.Delete.ConditionExpression,
+				ExpressionAttributeNames:  item.Delete.ExpressionAttributeNames,
+				ExpressionAttributeValues: item.Delete.ExpressionAttributeValues,
+			})
+			return req
+		}
+	}
+
+	err := d.retry(ctx, op, func() error {
+		return d.send(ctx, build())
+	})
+	if conditionFailed(err) && write.failed != nil {
+		return backend.NewError(PACKAGE_NAME, op, write.failed, errors.Unwrap(err))
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+func Test_nameKey(t *testing.T) {
+	d := &Driver{SourceID: "sid", EdgeTableName: "edges"}
+
+	// + test
+	key := d.nameKey("root", "a")
+	if *key[*EDGE_HASH].S != "sid:root" || *key[*EDGE_RANGE].S != "sid#name#a" {
+		t.Errorf("expected sid:root and sid#name#a got %s and %s", *key[*EDGE_HASH].S, *key[*EDGE_RANGE].S)
+	}
+
+	// - test
+	// the lock is never taken for an edge, even to a nid that looks like it
+	if strings.HasPrefix(*key[*EDGE_RANGE].S, "sid:") || *d.edgeKey("root", "#name#a")[*EDGE_RANGE].S == *key[*EDGE_RANGE].S {
+		t.Errorf("expected the lock key apart from the edge keys got %s", *key[*EDGE_RANGE].S)
+	}
+}
+
+func Test_edgeItems(t *testing.T) {
+	d := &Driver{SourceID: "sid", EdgeTableName: "edges"}
+	descriptions := func(writes []txWrite) string {
+		all := make([]string, len(writes))
+		for i, write := range writes {
+			all[i] = write.description
+		}
+		return strings.Join(all, ",")
+	}
+
+	// + test
+	items, writes := d.createEdgeItems("root", "1", d.edgeKey("root", "1"), "a")
+	if descriptions(writes) != "put edge root 1,lock name root a" || items[1].Put == nil {
+		t.Errorf("expected the edge and the lock put got %s", descriptions(writes))
+	}
+	if !errors.Is(writes[1].failed, backend.ErrAlreadyExists) {
+		t.Errorf("expected a taken name to be reported as already existing got %v", writes[1].failed)
+	}
+
+	items, writes = d.renameEdgeItems("root", "1", "a", "b", nil, map[string]*string{}, nil)
+	if descriptions(writes) != "update edge root 1,unlock name root a,lock name root b" {
+		t.Errorf("expected the lock to move got %s", descriptions(writes))
+	}
+	if update := items[0].Update; *update.ConditionExpression != "attribute_exists(#from) AND #name = :name" || *update.ExpressionAttributeValues[":name"].S != "a" {
+		t.Errorf("expected the update to need the old name got %v", update)
+	}
+
+	items, writes = d.deleteEdgeItems("root", "1", "a")
+	if descriptions(writes) != "delete edge root 1,unlock name root a" || items[1].Delete == nil {
+		t.Errorf("expected the edge and the lock deleted got %s", descriptions(writes))
+	}
+
+	// - test
+	// edges without a name leave the locks alone
+	if _, writes := d.createEdgeItems("root", "1", d.edgeKey("root", "1"), ""); len(writes) != 1 {
+		t.Errorf("expected only the edge put got %s", descriptions(writes))
+	}
+	items, writes = d.deleteEdgeItems("root", "1", "")
+	if len(writes) != 1 || *items[0].Delete.ConditionExpression != "attribute_exists(#from) AND attribute_not_exists(#name)" || items[0].Delete.ExpressionAttributeValues != nil {
+		t.Errorf("expected only the edge delete with no name got %v", items)
+	}
+	if _, writes := d.renameEdgeItems("root", "1", "", "b", nil, map[string]*string{}, nil); descriptions(writes) != "update edge root 1,lock name root b" {
+		t.Errorf("expected only the new name locked got %s", descriptions(writes))
+	}
+}
+
+func Test_txNames(t *testing.T) {
+	tx := &txDriver{Driver: &Driver{NodeTableName: "nodes", EdgeTableName: "edges", SourceID: "sid"}, keys: map[string]string{}, nodes: map[string]bool{}, names: map[[2]string]string{}}
+	named := func(name string) *backend.Properties {
+		properties := &backend.Properties{}
+		properties.SetString("name", name)
+		return properties
+	}
+
+	// + test
+	results, _ := tx.CreateEdges(context.Background(), &backend.Edges{"root": {"1": named("a")}, "2": {"1": named("a")}})
+	if results["root"]["1"] != nil || results["2"]["1"] != nil {
+		t.Fatalf("expected the same name under different parents got %v", results)
+	}
+	if len(tx.items) != 4 {
+		t.Errorf("expected each edge put along with its lock got %d items", len(tx.items))
+	}
+
+	// - test
+	results, _ = tx.CreateEdges(context.Background(), &backend.Edges{"root": {"3": named("a")}})
+	if !errors.Is(results["root"]["3"], backend.ErrAlreadyExists) {
+		t.Errorf("expected a name taken in the transaction to already exist got %v", results["root"]["3"])
+	}
+	if len(tx.items) != 4 {
+		t.Errorf("expected nothing added for the refused edge got %d items", len(tx.items))
+	}
+}
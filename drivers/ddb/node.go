@@ -1,107 +1,220 @@
 package ddb
 
 import (
+	"context"
 	"fmt"
+	"sort"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/sir-wiggles/bcfs/backend"
 )
 
-// Given a list of node ids return all the nodes and their properties
-func (d *Driver) GetNodes(nodes *backend.Nodes) error {
+// Given a list of node ids return all the nodes and their properties.  Nodes that are not found are left as is
+// and reported as backend.ErrNotFound.  The nodes are read in chunks of MAX_BATCH_GET_KEYS sent in parallel.
+func (d *Driver) GetNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
 
-	var err error
-	var sid = d.SourceID
-	keys := make([]map[string]*dynamodb.AttributeValue, 0, 100)
-	items := make([]map[string]*dynamodb.AttributeValue, 0, len(*nodes))
-	subSet := make([]map[string]*dynamodb.AttributeValue, 0, 100)
-	for nid, _ := range *nodes {
-		hash := aws.String(fmt.Sprintf("%s:%s", sid, nid))
-		key := map[string]*dynamodb.AttributeValue{
-			*NODE_HASH:  &dynamodb.AttributeValue{S: hash},
-			*NODE_RANGE: &dynamodb.AttributeValue{S: aws.String(nid)},
-		}
-		keys = append(keys, key)
-		if len(keys) == 100 {
-			subSet, err = d.batchGet(d.NodeTableName, keys)
-			if err != nil {
-				return err
-			}
-			keys = make([]map[string]*dynamodb.AttributeValue, 0, 100)
-			items = append(items, subSet...)
-		}
+	results := make(backend.NodeResults, len(*nodes))
+	nids := make([]string, 0, len(*nodes))
+	for nid := range *nodes {
+		results[nid] = backend.ErrNotFound
+		nids = append(nids, nid)
 	}
-	if len(keys) > 0 {
-		subSet, err = d.batchGet(d.NodeTableName, keys)
+	sort.Strings(nids)
+
+	keys := make([]map[string]*dynamodb.AttributeValue, len(nids))
+	for i, nid := range nids {
+		keys[i] = d.nodeKey(nid)
 	}
+	items, err := d.fanOut(ctx, d.batchGets(d.NodeTableName, keys))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	items = append(items, subSet...)
 
 	for _, item := range items {
-		node := nodes.GetNodeByID(*item["nid"].S)
-		for key, value := range item {
-			field := getFieldOfInterest(value)
-			switch field {
-			case "S":
-				node.SetString(key, *value.S)
-			case "B":
-				node.SetBinary(key, value.B)
-			case "N":
-				node.SetNumber(key, *value.N)
-			// this should be ok given we only use the above three fields
-			// boto puts bools up to dynamo as numbers :D
-			case "BOOL", "BS", "L", "M", "NS", "NULL", "SS":
-				return fmt.Errorf("dynamodb type %s is not implemented", field)
-			case "":
-				return fmt.Errorf("no field found for %s", node)
-			}
+		nid := *item[*NODE_RANGE].S
+		results[nid] = unmarshalItem(item, nodes.GetNodeByID(nid))
+	}
+	return results, nil
+}
+
+// CreateNodes puts the nodes into the node table.  A node whose nid is already in use is left unchanged and
+// reported as backend.ErrAlreadyExists, the same as neo does.  The puts are sent in parallel.
+func (d *Driver) CreateNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
+
+	results := make(backend.NodeResults, len(*nodes))
+	nids := make([]string, 0, len(*nodes))
+	items := make([]map[string]*dynamodb.AttributeValue, 0, len(*nodes))
+	for nid, properties := range *nodes {
+		item, err := marshalProperties(properties)
+		if err != nil {
+			results[nid] = err
+			continue
+		}
+		for key, value := range d.nodeKey(nid) {
+			item[key] = value
 		}
+		nids = append(nids, nid)
+		items = append(items, item)
 	}
-	return nil
+
+	errs, err := d.forEach(ctx, len(items), func(ctx context.Context, i int) error {
+		return d.putNew(ctx, d.NodeTableName, items[i], *NODE_HASH)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, nid := range nids {
+		results[nid] = errs[i]
+	}
+	return results, nil
 }
 
-func (d *Driver) CreateNodes(nodes *backend.Nodes) error {
+// AlterNodes sets the given properties on existing nodes and fills in the updated nodes.  A property given as
+// nil is removed.  The key attributes can not be altered, and a node that would change them is reported as
+// backend.ErrConflict.  Nodes that do not exist are left alone rather than being created, the same as neo does
+// for a MATCH that finds nothing, and are reported as backend.ErrNotFound.
+func (d *Driver) AlterNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
 
-	items := make([]*dynamodb.WriteRequest, 0, 25)
-	groups := make([]map[string][]*dynamodb.BatchWriteItemInput, 0, 1)
+	results := make(backend.NodeResults, len(*nodes))
+	unchanged := make(backend.Nodes)
 	for nid, properties := range *nodes {
+		key := d.nodeKey(nid)
+		if err := keyChange("UpdateItem", properties, key); err != nil {
+			results[nid] = err
+			continue
+		}
+		expression, names, values, err := setExpression(properties, *NODE_HASH, *NODE_RANGE)
+		if err != nil {
+			results[nid] = err
+			continue
+		}
+		if expression == nil {
+			// nothing to change, only check the node exists
+			unchanged[nid] = &backend.Properties{}
+			continue
+		}
 
-		item := make(map[string]*dynamodb.AttributeValue, 0, len(properties))
-		for key, property := range properties {
-			switch property.Type {
-			case backend.StringProperty:
-				value := property.Value.(string)
-				item[key] = &dynamodb.AttributeValue{S: aws.String(value)}
-			case backend.NumberProperty:
-				value := property.Value.(int)
-				item[key] = &dynamodb.AttributeValue{N: aws.String(value)}
-			case backend.BinaryProperty:
-				value := property.Value.(int)
-				item[key] = &dynamodb.AttributeValue{N: aws.String(value)}
-			}
+		var output *dynamodb.UpdateItemOutput
+		err = d.retry(ctx, "UpdateItem", func() error {
+			var req *request.Request
+			req, output = d.Connection.UpdateItemRequest(&dynamodb.UpdateItemInput{
+				TableName:                 aws.String(d.NodeTableName),
+				Key:                       key,
+				UpdateExpression:          expression,
+				ConditionExpression:       aws.String(fmt.Sprintf("attribute_exists(%s)", *NODE_HASH)),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+				ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+			})
+			return d.send(ctx, req)
+		})
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if conditionFailed(err) {
+			err = backend.ErrNotFound
+		}
+		if err == nil {
+			node := &backend.Properties{}
+			err = unmarshalItem(output.Attributes, node)
+			(*nodes)[nid] = node
+		}
+		results[nid] = err
+	}
+
+	if len(unchanged) > 0 {
+		found, err := d.GetNodes(ctx, &unchanged)
+		if err != nil {
+			return nil, err
 		}
-		items = append(items, dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}})
-		if len(items) == 25 {
-			groups = append(groups, map[string]*dynamodb.BatchWriteItemInput{d.NodeTableName: items})
-			items = make([]*dynamodb.WriteRequest, 0, 25)
+		for nid, err := range found {
+			results[nid] = err
+			if err == nil {
+				(*nodes)[nid] = unchanged[nid]
+			}
 		}
 	}
-	if len(items) > 0 {
-		groups = append(groups, items)
+	return results, nil
+}
+
+// DeleteNodes removes the given nodes from the node table along with the edges going out of and coming into them,
+// the same as a DETACH DELETE in neo.  Deleting a node that does not exist is not an error.  A node whose edges
+// could not all be deleted is left in place and reported with the error of the edge.
+func (d *Driver) DeleteNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
+
+	edges, err := d.attached(ctx, nodes)
+	if err != nil {
+		return nil, err
 	}
-	err := d.batchWrite(groups)
-	return err
+	edgeResults, err := d.DeleteEdges(ctx, &edges)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(backend.NodeResults, len(*nodes))
+	nids := make([]string, 0, len(*nodes))
+	requests := make([]*dynamodb.WriteRequest, 0, len(*nodes))
+	for nid := range *nodes {
+		if err := detached(edgeResults, nid); err != nil {
+			results[nid] = err
+			continue
+		}
+		nids = append(nids, nid)
+		requests = append(requests, &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: d.nodeKey(nid)}})
+	}
+	return d.nodeWriteResults(ctx, results, nids, requests)
 }
 
-func (d *Driver) AlterNodes(nodes *backend.Nodes) error {
+// attached returns the edges going out of and coming into the nodes, keyed by the from nid and then the to nid
+func (d *Driver) attached(ctx context.Context, nodes *backend.Nodes) (backend.Edges, error) {
+	out := make(backend.Edges, len(*nodes))
+	in := make(backend.Edges, len(*nodes))
+	for nid := range *nodes {
+		out[nid] = map[string]*backend.Properties{}
+		in[nid] = map[string]*backend.Properties{}
+	}
+	if _, err := d.GetOutEdges(ctx, &out); err != nil {
+		return nil, err
+	}
+	if _, err := d.GetInEdges(ctx, &in); err != nil {
+		return nil, err
+	}
 
-	items := make([]*dynamodb.WriteRequest, 0, 25)
-	for nid, properties := range *nodes {
+	edges := make(backend.Edges)
+	for fid, tos := range out {
+		for tid := range tos {
+			edges.GetEdgeByID(fid, tid)
+		}
+	}
+	for tid, froms := range in {
+		for fid := range froms {
+			edges.GetEdgeByID(fid, tid)
+		}
+	}
+	return edges, nil
+}
 
-		d.Connection.UpdateItem(&dynamodb.UpdateItemInput{})
+// detached returns the error of the first edge out of or into nid that could not be deleted, if any
+func detached(results backend.EdgeResults, nid string) error {
+	for _, pair := range results.Failed() {
+		if pair[0] == nid || pair[1] == nid {
+			return results[pair[0]][pair[1]]
+		}
 	}
 	return nil
 }
+
+// nodeWriteResults batch writes the requests to the node table and records the outcome of each one against the
+// nid at the same index
+func (d *Driver) nodeWriteResults(ctx context.Context, results backend.NodeResults, nids []string, requests []*dynamodb.WriteRequest) (backend.NodeResults, error) {
+	errs, err := d.batchWrite(ctx, d.NodeTableName, requests)
+	if err != nil {
+		return nil, err
+	}
+	for i, nid := range nids {
+		results[nid] = errs[i]
+	}
+	return results, nil
+}
@@ -0,0 +1,80 @@
+package ddb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+func Test_setExpression(t *testing.T) {
+	properties := &backend.Properties{"nid": nil, "size": nil}
+	properties.SetString("type", "folder")
+
+	// + test
+	expression, names, values, err := setExpression(properties, "nid")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if *expression != "SET #p1 = :p1 REMOVE #p0, #t0, #t1" {
+		t.Errorf("unexpected expression %s", *expression)
+	}
+	if *names["#p0"] != "size" || *names["#t1"] != typeAttr("type") || *values[":p1"].S != "folder" {
+		t.Errorf("unexpected names %v or values %v", names, values)
+	}
+
+	// only removes leave the values out since dynamodb refuses an empty map
+	expression, _, values, err = setExpression(&backend.Properties{"size": nil})
+	if err != nil || *expression != "REMOVE #p0, #t0" || values != nil {
+		t.Errorf("unexpected expression %v with values %v", *expression, values)
+	}
+
+	// - test
+	if expression, _, _, _ := setExpression(properties, "nid", "size", "type"); expression != nil {
+		t.Errorf("expected nothing to change got %s", *expression)
+	}
+}
+
+func Test_keyChange(t *testing.T) {
+	d := &Driver{SourceID: "sid"}
+	key := d.nodeKey("1")
+
+	// + test
+	// the keys as they come back from a get are fine
+	properties := &backend.Properties{}
+	properties.SetString(*NODE_HASH, "sid:1")
+	properties.SetString(*NODE_RANGE, "1")
+	properties.SetString("type", "file")
+	if err := keyChange("UpdateItem", properties, key); err != nil {
+		t.Errorf("expected no change got %v", err)
+	}
+
+	// - test
+	properties.SetString(*NODE_RANGE, "2")
+	if err := keyChange("UpdateItem", properties, key); !errors.Is(err, backend.ErrConflict) {
+		t.Errorf("expected a conflict got %v", err)
+	}
+	if err := keyChange("UpdateItem", &backend.Properties{*NODE_HASH: nil}, key); !errors.Is(err, backend.ErrConflict) {
+		t.Errorf("expected removing a key to conflict got %v", err)
+	}
+}
+
+func Test_detached(t *testing.T) {
+	results := backend.EdgeResults{}
+	results.Set("root", "1", nil)
+	results.Set("1", "2", backend.ErrConflict)
+
+	// + test
+	if err := detached(results, "root"); err != nil {
+		t.Errorf("expected root to be detached got %v", err)
+	}
+
+	// - test
+	// a failed edge keeps the nodes at both of its ends
+	if err := detached(results, "1"); !errors.Is(err, backend.ErrConflict) {
+		t.Errorf("expected the conflict of 1 -> 2 got %v", err)
+	}
+	if err := detached(results, "2"); !errors.Is(err, backend.ErrConflict) {
+		t.Errorf("expected the conflict of 1 -> 2 got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package ddb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// GetPath resolves a slash separated path starting at the root nid.  Each level is looked up by the edge name
+// through the name-index LSI on the edge table.
+func (d *Driver) GetPath(ctx context.Context, root string, path string) (*backend.Path, error) {
+
+	p, err := backend.ResolvePath(ctx, root, path, d.lookupEdgeByName)
+	if err != nil {
+		return nil, err
+	}
+	return p, p.FillNodes(ctx, d.GetNodes)
+}
+
+// lookupEdgeByName finds the edge going out of the parent that has the given name and returns the nid it points
+// to along with the edge properties, or an empty nid if there is no such edge.  The name-index LSI only projects
+// the keys and the name so the edge itself is read from the table once its nid is known.
+func (d *Driver) lookupEdgeByName(ctx context.Context, parent, name string) (string, *backend.Properties, error) {
+
+	items, err := d.query(ctx, d.nameQuery(parent, name))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(items) == 0 {
+		return "", nil, nil
+	}
+	_, tid := edgeIDs(items[0])
+
+	var output *dynamodb.GetItemOutput
+	err = d.retry(ctx, "GetItem", func() error {
+		var req *request.Request
+		req, output = d.Connection.GetItemRequest(&dynamodb.GetItemInput{
+			TableName: aws.String(d.EdgeTableName),
+			Key:       d.edgeKey(parent, tid),
+		})
+		return d.send(ctx, req)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if output.Item == nil {
+		// deleted since the index was read
+		return "", nil, nil
+	}
+
+	edge := &backend.Properties{}
+	if err := unmarshalItem(output.Item, edge); err != nil {
+		return "", nil, err
+	}
+	return tid, edge, nil
+}
+
+// nameQuery returns the query of the name-index LSI for the edges going out of the parent with the given name
+func (d *Driver) nameQuery(parent, name string) *dynamodb.QueryInput {
+	return &dynamodb.QueryInput{
+		TableName: aws.String(d.EdgeTableName),
+		IndexName: EDGE_LSI_NAME,
+		ExpressionAttributeNames: map[string]*string{
+			"#from": EDGE_HASH,
+			"#name": EDGE_ATTR_NAME,
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":from": &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("%s:%s", d.SourceID, parent))},
+			":name": &dynamodb.AttributeValue{S: aws.String(name)},
+		},
+		KeyConditionExpression: aws.String("#from = :from AND #name = :name"),
+	}
+}
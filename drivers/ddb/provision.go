@@ -0,0 +1,333 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// PROVISION_POLL_INTERVAL is how often the status of a table is checked while waiting for it to become active
+var PROVISION_POLL_INTERVAL = 2 * time.Second
+
+// keySchema returns a key schema with the hash and range attributes
+func keySchema(hash, rng *string) []*dynamodb.KeySchemaElement {
+	return []*dynamodb.KeySchemaElement{
+		{AttributeName: hash, KeyType: aws.String(dynamodb.KeyTypeHash)},
+		{AttributeName: rng, KeyType: aws.String(dynamodb.KeyTypeRange)},
+	}
+}
+
+// stringAttributes returns the definitions of the string attributes with the given names
+func stringAttributes(names ...*string) []*dynamodb.AttributeDefinition {
+	definitions := make([]*dynamodb.AttributeDefinition, len(names))
+	for i, name := range names {
+		definitions[i] = &dynamodb.AttributeDefinition{AttributeName: name, AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)}
+	}
+	return definitions
+}
+
+// nodeTableInput returns the definition of the node table.  Without a capacity the table is billed per request.
+func nodeTableInput(name string, capacity *dynamodb.ProvisionedThroughput) *dynamodb.CreateTableInput {
+	return withCapacity(&dynamodb.CreateTableInput{
+		TableName:            aws.String(name),
+		AttributeDefinitions: stringAttributes(NODE_HASH, NODE_RANGE, NODE_ATTR_BLOCKLIST),
+		KeySchema:            keySchema(NODE_HASH, NODE_RANGE),
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{
+				IndexName:  NODE_GSI_BLOCKLIST,
+				KeySchema:  keySchema(NODE_HASH, NODE_ATTR_BLOCKLIST),
+				Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+			},
+		},
+	}, capacity)
+}
+
+// edgeTableInput returns the definition of the edge table.  Without a capacity the table is billed per request.
+func edgeTableInput(name string, capacity *dynamodb.ProvisionedThroughput) *dynamodb.CreateTableInput {
+	return withCapacity(&dynamodb.CreateTableInput{
+		TableName:            aws.String(name),
+		AttributeDefinitions: stringAttributes(EDGE_HASH, EDGE_RANGE, EDGE_ATTR_NAME),
+		KeySchema:            keySchema(EDGE_HASH, EDGE_RANGE),
+		LocalSecondaryIndexes: []*dynamodb.LocalSecondaryIndex{
+			{
+				IndexName: EDGE_LSI_NAME,
+				KeySchema: keySchema(EDGE_HASH, EDGE_ATTR_NAME),
+				Projection: &dynamodb.Projection{
+					ProjectionType:   aws.String(dynamodb.ProjectionTypeInclude),
+					NonKeyAttributes: []*string{EDGE_HASH, EDGE_RANGE, EDGE_ATTR_NAME},
+				},
+			},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{
+				IndexName:  EDGE_GSI_REVERSE,
+				KeySchema:  keySchema(EDGE_RANGE, EDGE_HASH),
+				Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+			},
+		},
+	}, capacity)
+}
+
+// withCapacity sets the billing of the table and its global indexes
+func withCapacity(input *dynamodb.CreateTableInput, capacity *dynamodb.ProvisionedThroughput) *dynamodb.CreateTableInput {
+	if capacity == nil {
+		input.BillingMode = aws.String(dynamodb.BillingModePayPerRequest)
+		return input
+	}
+	input.BillingMode = aws.String(dynamodb.BillingModeProvisioned)
+	input.ProvisionedThroughput = capacity
+	for _, index := range input.GlobalSecondaryIndexes {
+		index.ProvisionedThroughput = capacity
+	}
+	return input
+}
+
+// Provision creates the node and edge tables, and the global indexes of existing tables, that are missing and
+// waits for them to become active.  Tables are reported by name and indexes as "table/index".  An existing table
+// or index whose key schema is not the expected one is reported as a backend.ErrConflict, since keys can not be
+// changed in place, and so is a missing local index, which can only be added along with its table.
+func (d *Driver) Provision(ctx context.Context) (*backend.ProvisionReport, error) {
+
+	report := &backend.ProvisionReport{}
+	for _, input := range []*dynamodb.CreateTableInput{
+		nodeTableInput(d.NodeTableName, d.capacity),
+		edgeTableInput(d.EdgeTableName, d.capacity),
+	} {
+		if err := d.provisionTable(ctx, input, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// provisionTable creates the table or checks the existing one against input, recording what it did in report
+func (d *Driver) provisionTable(ctx context.Context, input *dynamodb.CreateTableInput, report *backend.ProvisionReport) error {
+
+	table := *input.TableName
+	description, err := d.describeTable(ctx, table)
+	if errors.Is(err, backend.ErrNotFound) {
+		req, _ := d.Connection.CreateTableRequest(input)
+		if err := d.send(ctx, req); err != nil {
+			return err
+		}
+		report.Created = append(report.Created, table)
+		for _, index := range input.LocalSecondaryIndexes {
+			report.Created = append(report.Created, table+"/"+*index.IndexName)
+		}
+		for _, index := range input.GlobalSecondaryIndexes {
+			report.Created = append(report.Created, table+"/"+*index.IndexName)
+		}
+		return d.waitForTable(ctx, table)
+	}
+	if err != nil {
+		return err
+	}
+
+	missing, err := checkTable(input, description)
+	if err != nil {
+		return backend.NewError(PACKAGE_NAME, "Provision", backend.ErrConflict, err)
+	}
+	report.Existing = append(report.Existing, table)
+	for _, index := range input.LocalSecondaryIndexes {
+		report.Existing = append(report.Existing, table+"/"+*index.IndexName)
+	}
+	for _, index := range input.GlobalSecondaryIndexes {
+		if !contains(missing, *index.IndexName) {
+			report.Existing = append(report.Existing, table+"/"+*index.IndexName)
+		}
+	}
+
+	// a table left behind by an earlier run may still be getting created
+	if !active(description) {
+		if err := d.waitForTable(ctx, table); err != nil {
+			return err
+		}
+	}
+
+	// dynamodb only adds one global index to a table at a time
+	for _, index := range input.GlobalSecondaryIndexes {
+		if !contains(missing, *index.IndexName) {
+			continue
+		}
+		// the index is billed the same way as the table it is added to
+		throughput := index.ProvisionedThroughput
+		if payPerRequest(description) {
+			throughput = nil
+		} else if throughput == nil {
+			throughput = &dynamodb.ProvisionedThroughput{
+				ReadCapacityUnits:  description.ProvisionedThroughput.ReadCapacityUnits,
+				WriteCapacityUnits: description.ProvisionedThroughput.WriteCapacityUnits,
+			}
+		}
+		update := &dynamodb.UpdateTableInput{
+			TableName:            input.TableName,
+			AttributeDefinitions: input.AttributeDefinitions,
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+				{Create: &dynamodb.CreateGlobalSecondaryIndexAction{
+					IndexName:             index.IndexName,
+					KeySchema:             index.KeySchema,
+					Projection:            index.Projection,
+					ProvisionedThroughput: throughput,
+				}},
+			},
+		}
+		req, _ := d.Connection.UpdateTableRequest(update)
+		if err := d.send(ctx, req); err != nil {
+			return err
+		}
+		report.Created = append(report.Created, table+"/"+*index.IndexName)
+		if err := d.waitForTable(ctx, table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeTable returns the description of the table, or a backend.ErrNotFound if it does not exist
+func (d *Driver) describeTable(ctx context.Context, table string) (*dynamodb.TableDescription, error) {
+	req, output := d.Connection.DescribeTableRequest(&dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if err := d.send(ctx, req); err != nil {
+		return nil, err
+	}
+	return output.Table, nil
+}
+
+// waitForTable polls the table until it and every one of its global indexes are active
+func (d *Driver) waitForTable(ctx context.Context, table string) error {
+	ticker := time.NewTicker(PROVISION_POLL_INTERVAL)
+	defer ticker.Stop()
+	for {
+		description, err := d.describeTable(ctx, table)
+		if err != nil && !errors.Is(err, backend.ErrNotFound) {
+			return err
+		}
+		if description != nil && active(description) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// payPerRequest reports whether the table is billed per request rather than by provisioned capacity
+func payPerRequest(description *dynamodb.TableDescription) bool {
+	return description.BillingModeSummary != nil &&
+		aws.StringValue(description.BillingModeSummary.BillingMode) == dynamodb.BillingModePayPerRequest
+}
+
+// active reports whether the table and all of its global indexes are active
+func active(description *dynamodb.TableDescription) bool {
+	if aws.StringValue(description.TableStatus) != dynamodb.TableStatusActive {
+		return false
+	}
+	for _, index := range description.GlobalSecondaryIndexes {
+		if aws.StringValue(index.IndexStatus) != dynamodb.IndexStatusActive {
+			return false
+		}
+	}
+	return true
+}
+
+// checkTable compares an existing table with its expected definition and returns the names of the global
+// indexes that are missing from it.  Any other difference in the keys of the table or its indexes is an error.
+func checkTable(expected *dynamodb.CreateTableInput, description *dynamodb.TableDescription) ([]string, error) {
+
+	table := *expected.TableName
+	var problems []string
+	if !sameKeys(expected.KeySchema, description.KeySchema) {
+		problems = append(problems, fmt.Sprintf("the key is %s instead of %s",
+			describeKeys(description.KeySchema), describeKeys(expected.KeySchema)))
+	}
+
+	// key attributes have to be strings since the driver writes them as such
+	types := make(map[string]string, len(description.AttributeDefinitions))
+	for _, definition := range description.AttributeDefinitions {
+		types[*definition.AttributeName] = *definition.AttributeType
+	}
+	for _, definition := range expected.AttributeDefinitions {
+		if t, ok := types[*definition.AttributeName]; ok && t != *definition.AttributeType {
+			problems = append(problems, fmt.Sprintf("attribute %s is of type %s instead of %s",
+				*definition.AttributeName, t, *definition.AttributeType))
+		}
+	}
+
+	lsis := make(map[string][]*dynamodb.KeySchemaElement, len(description.LocalSecondaryIndexes))
+	for _, index := range description.LocalSecondaryIndexes {
+		lsis[*index.IndexName] = index.KeySchema
+	}
+	for _, index := range expected.LocalSecondaryIndexes {
+		keys, ok := lsis[*index.IndexName]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("local index %s is missing and can only be added by recreating the table", *index.IndexName))
+		case !sameKeys(index.KeySchema, keys):
+			problems = append(problems, fmt.Sprintf("local index %s has the key %s instead of %s",
+				*index.IndexName, describeKeys(keys), describeKeys(index.KeySchema)))
+		}
+	}
+
+	gsis := make(map[string][]*dynamodb.KeySchemaElement, len(description.GlobalSecondaryIndexes))
+	for _, index := range description.GlobalSecondaryIndexes {
+		gsis[*index.IndexName] = index.KeySchema
+	}
+	var missing []string
+	for _, index := range expected.GlobalSecondaryIndexes {
+		keys, ok := gsis[*index.IndexName]
+		switch {
+		case !ok:
+			missing = append(missing, *index.IndexName)
+		case !sameKeys(index.KeySchema, keys):
+			problems = append(problems, fmt.Sprintf("global index %s has the key %s instead of %s",
+				*index.IndexName, describeKeys(keys), describeKeys(index.KeySchema)))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("table %s does not match: %s", table, strings.Join(problems, "; "))
+	}
+	return missing, nil
+}
+
+// sameKeys reports whether two key schemas have the same attributes with the same key types
+func sameKeys(a, b []*dynamodb.KeySchemaElement) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	types := make(map[string]string, len(a))
+	for _, key := range a {
+		types[*key.AttributeName] = *key.KeyType
+	}
+	for _, key := range b {
+		if t, ok := types[*key.AttributeName]; !ok || t != *key.KeyType {
+			return false
+		}
+	}
+	return true
+}
+
+// describeKeys formats a key schema for an error message, e.g. (sid_from HASH, sid_to RANGE)
+func describeKeys(keys []*dynamodb.KeySchemaElement) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = *key.AttributeName + " " + *key.KeyType
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package ddb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// describe returns the description dynamodb would give of a table created from input
+func describe(input *dynamodb.CreateTableInput) *dynamodb.TableDescription {
+	description := &dynamodb.TableDescription{
+		TableName:            input.TableName,
+		TableStatus:          aws.String(dynamodb.TableStatusActive),
+		AttributeDefinitions: input.AttributeDefinitions,
+		KeySchema:            input.KeySchema,
+	}
+	for _, index := range input.LocalSecondaryIndexes {
+		description.LocalSecondaryIndexes = append(description.LocalSecondaryIndexes,
+			&dynamodb.LocalSecondaryIndexDescription{IndexName: index.IndexName, KeySchema: index.KeySchema})
+	}
+	for _, index := range input.GlobalSecondaryIndexes {
+		description.GlobalSecondaryIndexes = append(description.GlobalSecondaryIndexes,
+			&dynamodb.GlobalSecondaryIndexDescription{IndexName: index.IndexName, KeySchema: index.KeySchema})
+	}
+	return description
+}
+
+func Test_checkTable(t *testing.T) {
+	expected := edgeTableInput("edges", nil)
+
+	// + test
+	missing, err := checkTable(expected, describe(expected))
+	if err != nil || len(missing) != 0 {
+		t.Errorf("expected a match got %v %v", missing, err)
+	}
+
+	description := describe(expected)
+	description.GlobalSecondaryIndexes = nil
+	missing, err = checkTable(expected, description)
+	if err != nil || len(missing) != 1 || missing[0] != *EDGE_GSI_REVERSE {
+		t.Errorf("expected the reverse index to be missing got %v %v", missing, err)
+	}
+
+	// - test
+	description = describe(expected)
+	description.KeySchema = keySchema(EDGE_RANGE, EDGE_HASH)
+	if _, err := checkTable(expected, description); err == nil || !strings.Contains(err.Error(), "the key is") {
+		t.Errorf("expected the swapped key to be an error got %v", err)
+	}
+
+	description = describe(expected)
+	description.LocalSecondaryIndexes = nil
+	if _, err := checkTable(expected, description); err == nil || !strings.Contains(err.Error(), *EDGE_LSI_NAME) {
+		t.Errorf("expected the missing local index to be an error got %v", err)
+	}
+
+	description = describe(expected)
+	description.AttributeDefinitions = []*dynamodb.AttributeDefinition{
+		{AttributeName: EDGE_HASH, AttributeType: aws.String(dynamodb.ScalarAttributeTypeN)},
+	}
+	if _, err := checkTable(expected, description); err == nil || !strings.Contains(err.Error(), "type N") {
+		t.Errorf("expected the number key to be an error got %v", err)
+	}
+}
+
+func Test_withCapacity(t *testing.T) {
+	input := nodeTableInput("nodes", nil)
+	if *input.BillingMode != dynamodb.BillingModePayPerRequest || input.ProvisionedThroughput != nil {
+		t.Errorf("expected pay per request got %v", input)
+	}
+
+	capacity := &dynamodb.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(5), WriteCapacityUnits: aws.Int64(5)}
+	input = nodeTableInput("nodes", capacity)
+	if *input.BillingMode != dynamodb.BillingModeProvisioned || input.GlobalSecondaryIndexes[0].ProvisionedThroughput != capacity {
+		t.Errorf("expected the table and its indexes to be provisioned got %v", input)
+	}
+}
@@ -0,0 +1,136 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// Defaults of the retry budget used when the config leaves them out
+var (
+	DEFAULT_RETRY_ATTEMPTS   = 10
+	DEFAULT_RETRY_BASE_DELAY = 25 * time.Millisecond
+	DEFAULT_RETRY_MAX_DELAY  = 5 * time.Second
+)
+
+// retryPolicy is how many times, and how far apart, the driver retries a request that was throttled, failed
+// for a reason that may go away or left keys or items unprocessed.  This is on top of the retries the aws sdk
+// makes of a single call.  Zero values are replaced by the defaults.
+type retryPolicy struct {
+	// attempts is the most times a request is made, counting the first
+	attempts int
+	// baseDelay is the longest wait before the first retry, which doubles with every retry up to maxDelay
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+// withDefaults returns the policy with the defaults filled in
+func (p retryPolicy) withDefaults() retryPolicy {
+	if p.attempts < 1 {
+		p.attempts = DEFAULT_RETRY_ATTEMPTS
+	}
+	if p.baseDelay <= 0 {
+		p.baseDelay = DEFAULT_RETRY_BASE_DELAY
+	}
+	if p.maxDelay <= 0 {
+		p.maxDelay = DEFAULT_RETRY_MAX_DELAY
+	}
+	return p
+}
+
+// ceiling returns the longest wait before the given retry, counting from 1
+func (p retryPolicy) ceiling(retry int) time.Duration {
+	delay := p.baseDelay
+	for i := 1; i < retry && delay < p.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+	return delay
+}
+
+// RetryError is returned when a request was still failing, or still leaving keys or items unprocessed, once the
+// retry budget ran out.  It comes wrapped in a backend.Error of the same kind as the last failure, which is
+// backend.ErrThrottled when dynamodb only kept leaving work unprocessed.
+type RetryError struct {
+	Attempts int
+	// Unprocessed is the number of keys or items that were left, 0 when the last attempt failed outright
+	Unprocessed int
+	// Err is the error of the last attempt, nil when it only left work unprocessed
+	Err error
+}
+
+func (e *RetryError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("gave up after %d attempts with %d unprocessed", e.Attempts, e.Unprocessed)
+	}
+	return fmt.Sprintf("gave up after %d attempts: %s", e.Attempts, e.Err.Error())
+}
+
+// Unwrap returns the error of the last attempt
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// retryable reports whether a request that failed with err is worth making again.  Everything that is not
+// throttling or the service being unavailable, validation errors included, would only fail the same way again.
+func retryable(err error) bool {
+	return errors.Is(err, backend.ErrThrottled) || errors.Is(err, backend.ErrUnavailable)
+}
+
+// backoff counts the attempts of a single request against the retry budget of the driver
+type backoff struct {
+	op      string
+	policy  retryPolicy
+	attempt int
+}
+
+// backoff starts counting the attempts of a request for the operation op
+func (d Driver) backoff(op string) *backoff {
+	return &backoff{op: op, policy: d.retries.withDefaults(), attempt: 1}
+}
+
+// wait sleeps for a random time up to the ceiling of the next retry, the "full jitter" of the aws architecture
+// blog, so clients throttled together do not come back together.  err is the error of the attempt that was
+// just made, or nil if it left unprocessed keys or items behind.  The RetryError is returned instead once the
+// budget is spent, and the context error if it is done first.
+func (b *backoff) wait(ctx context.Context, err error, unprocessed int) error {
+	if b.attempt >= b.policy.attempts {
+		kind := backend.ErrThrottled
+		if err != nil && !errors.Is(err, backend.ErrThrottled) {
+			kind = backend.ErrUnavailable
+		}
+		return backend.NewError(PACKAGE_NAME, b.op, kind, &RetryError{Attempts: b.attempt, Unprocessed: unprocessed, Err: err})
+	}
+
+	delay := time.Duration(rand.Int63n(int64(b.policy.ceiling(b.attempt)) + 1))
+	b.attempt++
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retry calls attempt until it succeeds, fails with an error that is not worth retrying or the retry budget of
+// op is spent
+func (d Driver) retry(ctx context.Context, op string, attempt func() error) error {
+	b := d.backoff(op)
+	for {
+		err := attempt()
+		if err == nil || !retryable(err) {
+			return err
+		}
+		if err := b.wait(ctx, err, 0); err != nil {
+			return err
+		}
+	}
+}
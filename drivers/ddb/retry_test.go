@@ -0,0 +1,105 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+func Test_ceiling(t *testing.T) {
+	p := retryPolicy{baseDelay: 10 * time.Millisecond, maxDelay: 50 * time.Millisecond}
+
+	// + test
+	for retry, expected := range map[int]time.Duration{1: 10, 2: 20, 3: 40, 4: 50, 100: 50} {
+		if ceiling := p.ceiling(retry); ceiling != expected*time.Millisecond {
+			t.Errorf("retry %d: expected %s got %s", retry, expected*time.Millisecond, ceiling)
+		}
+	}
+}
+
+func Test_retryable(t *testing.T) {
+
+	// + test
+	for _, code := range []string{dynamodb.ErrCodeProvisionedThroughputExceededException, dynamodb.ErrCodeInternalServerError} {
+		if err := newError("Query", awserr.New(code, "", nil)); !retryable(err) {
+			t.Errorf("expected %s to be retryable", code)
+		}
+	}
+
+	// - test
+	for _, code := range []string{dynamodb.ErrCodeConditionalCheckFailedException, "ValidationException"} {
+		if err := newError("Query", awserr.New(code, "", nil)); retryable(err) {
+			t.Errorf("expected %s not to be retryable", code)
+		}
+	}
+	if retryable(context.Canceled) {
+		t.Errorf("expected a cancelled context not to be retryable")
+	}
+}
+
+func Test_retry(t *testing.T) {
+	d := Driver{retries: retryPolicy{attempts: 3, baseDelay: time.Microsecond, maxDelay: time.Millisecond}}
+	throttled := newError("PutItem", awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "", nil))
+
+	// + test
+	calls := 0
+	err := d.retry(context.Background(), "PutItem", func() error {
+		if calls++; calls < 3 {
+			return throttled
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success on the third call got %v after %d calls", err, calls)
+	}
+
+	// - test
+	calls = 0
+	err = d.retry(context.Background(), "PutItem", func() error {
+		calls++
+		return throttled
+	})
+	var rerr *RetryError
+	if !errors.Is(err, backend.ErrThrottled) || !errors.As(err, &rerr) || rerr.Attempts != 3 || calls != 3 {
+		t.Errorf("expected to give up throttled after 3 calls got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	conflict := newError("PutItem", awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil))
+	err = d.retry(context.Background(), "PutItem", func() error {
+		calls++
+		return conflict
+	})
+	if err != conflict || calls != 1 {
+		t.Errorf("expected the conflict without retrying got %v after %d calls", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d.retries = retryPolicy{attempts: 3, baseDelay: time.Hour, maxDelay: time.Hour}
+	err = d.retry(ctx, "PutItem", func() error { return throttled })
+	if err != context.Canceled {
+		t.Errorf("expected the context error got %v", err)
+	}
+}
+
+func Test_backoffUnprocessed(t *testing.T) {
+	b := Driver{retries: retryPolicy{attempts: 2, baseDelay: time.Microsecond}}.backoff("BatchGetItem")
+
+	// + test
+	if err := b.wait(context.Background(), nil, 5); err != nil {
+		t.Errorf("expected a retry got %v", err)
+	}
+
+	// - test
+	err := b.wait(context.Background(), nil, 5)
+	var rerr *RetryError
+	if !errors.Is(err, backend.ErrThrottled) || !errors.As(err, &rerr) || rerr.Unprocessed != 5 {
+		t.Errorf("expected to give up with 5 unprocessed got %v", err)
+	}
+}
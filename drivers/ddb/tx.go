@@ -0,0 +1,354 @@
+package ddb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// MAX_TX_ITEMS is the most writes dynamodb accepts in a single TransactWriteItems call
+var MAX_TX_ITEMS = 100
+
+// WithTx calls fn with a driver that collects the writes made through it and sends them all in a single
+// TransactWriteItems call once fn returns nil, so either every write happens or none of them do.  Reads go
+// straight to dynamodb and do not see the writes collected so far.  Since nothing is written until the end, the
+// results of a write only report whether it could be added to the transaction.  Creates must not find their
+// item, or for an edge another edge with its name, already there.  Alters must find the item they alter and
+// created edges must find the nodes at both ends, either already in the table or written by the same
+// transaction.  Otherwise the whole transaction is cancelled with a CancelledError, of kind
+// backend.ErrAlreadyExists for a create and backend.ErrConflict for the rest.  The name of an edge that is
+// renamed or deleted is read when the write is made, so the item locking it goes with it, and the write only
+// goes through if the edge still has that name at commit.  Dynamodb refuses a transaction that writes the same
+// item twice, so a second write of an item is not added and is reported as backend.ErrUnsupported.
+func (d *Driver) WithTx(ctx context.Context, fn func(backend.Graph) error) error {
+	tx := &txDriver{Driver: d, keys: map[string]string{}, nodes: map[string]bool{}, names: map[[2]string]string{}}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.commit(ctx)
+}
+
+// txDriver is the driver handed to the function given to WithTx.  The reads of Driver are used as they are and
+// the writes are replaced by ones that add to items.
+type txDriver struct {
+	*Driver
+	items []*dynamodb.TransactWriteItem
+	// writes describes each of the items for the reasons of a CancelledError
+	writes []txWrite
+	// keys are the items written so far along with the description of their write
+	keys map[string]string
+	// nodes are the nodes written by the transaction and endpoints the ends of the edges it creates
+	nodes     map[string]bool
+	endpoints []string
+	// names are the edge names locked by the transaction keyed by the from nid and the name, see nameKey
+	names map[[2]string]string
+}
+
+// WithTx joins the transaction in progress
+func (t *txDriver) WithTx(ctx context.Context, fn func(backend.Graph) error) error {
+	return fn(t)
+}
+
+// commit sends the collected writes along with their condition checks.  The transaction fails as a whole if
+// there are more of them than dynamodb allows.
+func (t *txDriver) commit(ctx context.Context) error {
+	items, writes := t.transactItems()
+	if len(items) == 0 {
+		return nil
+	}
+	if len(items) > MAX_TX_ITEMS {
+		return backend.NewError(PACKAGE_NAME, "TransactWriteItems", backend.ErrUnsupported,
+			fmt.Errorf("%d writes is more than the %d allowed in a transaction", len(items), MAX_TX_ITEMS))
+	}
+	return t.retry(ctx, "TransactWriteItems", func() error {
+		req, _ := t.Connection.TransactWriteItemsRequest(&dynamodb.TransactWriteItemsInput{
+			TransactItems: items,
+		})
+		return cancelled(t.send(ctx, req), writes)
+	})
+}
+
+// transactItems returns the collected writes followed by a check that every endpoint of a created edge exists
+// and their descriptions.  Nodes written by the transaction are not checked since dynamodb refuses two
+// operations on the same item in a transaction, and the write itself says what becomes of them.
+func (t *txDriver) transactItems() ([]*dynamodb.TransactWriteItem, []txWrite) {
+	items, writes := t.items, t.writes
+	checked := map[string]bool{}
+	for _, nid := range t.endpoints {
+		if t.nodes[nid] || checked[nid] {
+			continue
+		}
+		checked[nid] = true
+		items = append(items, &dynamodb.TransactWriteItem{
+			ConditionCheck: &dynamodb.ConditionCheck{
+				TableName:           aws.String(t.NodeTableName),
+				Key:                 t.nodeKey(nid),
+				ConditionExpression: aws.String(fmt.Sprintf("attribute_exists(%s)", *NODE_HASH)),
+			},
+		})
+		writes = append(writes, txWrite{description: fmt.Sprintf("check node %s", nid), failed: backend.ErrConflict})
+	}
+	return items, writes
+}
+
+// add adds an item to the transaction along with its description and the kind of error its condition failing is
+// reported as
+func (t *txDriver) add(item *dynamodb.TransactWriteItem, failed error, write string, args ...interface{}) error {
+	return t.addAll([]*dynamodb.TransactWriteItem{item}, []txWrite{{description: fmt.Sprintf(write, args...), failed: failed}})
+}
+
+// addAll adds the items to the transaction along with their descriptions, or none of them if one writes an item
+// already written by the transaction
+func (t *txDriver) addAll(items []*dynamodb.TransactWriteItem, writes []txWrite) error {
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = t.transactKey(item)
+		if err := t.taken(keys[i], writes[i].description); err != nil {
+			return err
+		}
+	}
+	for i, key := range keys {
+		t.keys[key] = writes[i].description
+	}
+	t.items = append(t.items, items...)
+	t.writes = append(t.writes, writes...)
+	return nil
+}
+
+// taken returns an error of kind backend.ErrUnsupported if the item with the key is already written by the
+// transaction
+func (t *txDriver) taken(key string, description string) error {
+	if prior, ok := t.keys[key]; ok {
+		return backend.NewError(PACKAGE_NAME, "TransactWriteItems", backend.ErrUnsupported,
+			fmt.Errorf("%s writes the same item as %s", description, prior))
+	}
+	return nil
+}
+
+// transactKey identifies the item written or checked by an item of a transaction
+func (t *txDriver) transactKey(item *dynamodb.TransactWriteItem) string {
+	switch {
+	case item.Put != nil:
+		return t.itemKey(aws.StringValue(item.Put.TableName), item.Put.Item)
+	case item.Update != nil:
+		return t.itemKey(aws.StringValue(item.Update.TableName), item.Update.Key)
+	case item.Delete != nil:
+		return t.itemKey(aws.StringValue(item.Delete.TableName), item.Delete.Key)
+	}
+	return t.itemKey(aws.StringValue(item.ConditionCheck.TableName), item.ConditionCheck.Key)
+}
+
+// put adds a put of the item to the transaction on the condition that the item does not exist yet, hash being
+// the name of the hash key attribute
+func (t *txDriver) put(table string, key map[string]*dynamodb.AttributeValue, properties *backend.Properties, hash string, write string, args ...interface{}) error {
+	item, err := marshalProperties(properties)
+	if err != nil {
+		return err
+	}
+	for k, v := range key {
+		item[k] = v
+	}
+	return t.add(&dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName:           aws.String(table),
+			Item:                item,
+			ConditionExpression: aws.String(fmt.Sprintf("attribute_not_exists(%s)", hash)),
+		},
+	}, backend.ErrAlreadyExists, write, args...)
+}
+
+// update adds an update setting the properties of an existing item to the transaction, hash being the name of
+// the hash key attribute that must exist
+func (t *txDriver) update(table string, key map[string]*dynamodb.AttributeValue, properties *backend.Properties, hash string, keys []string, write string, args ...interface{}) error {
+	expression, names, values, err := setExpression(properties, keys...)
+	if err != nil {
+		return err
+	}
+	if expression == nil {
+		return nil
+	}
+	return t.add(&dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName:                 aws.String(table),
+			Key:                       key,
+			UpdateExpression:          expression,
+			ConditionExpression:       aws.String(fmt.Sprintf("attribute_exists(%s)", hash)),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		},
+	}, backend.ErrConflict, write, args...)
+}
+
+// delete adds a delete of the item to the transaction
+func (t *txDriver) delete(table string, key map[string]*dynamodb.AttributeValue, write string, args ...interface{}) error {
+	return t.add(&dynamodb.TransactWriteItem{
+		Delete: &dynamodb.Delete{TableName: aws.String(table), Key: key},
+	}, nil, write, args...)
+}
+
+func (t *txDriver) CreateNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
+	results := make(backend.NodeResults, len(*nodes))
+	for nid, properties := range *nodes {
+		t.nodes[nid] = true
+		results[nid] = t.put(t.NodeTableName, t.nodeKey(nid), properties, *NODE_HASH, "put node %s", nid)
+	}
+	return results, nil
+}
+
+func (t *txDriver) AlterNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
+	results := make(backend.NodeResults, len(*nodes))
+	for nid, properties := range *nodes {
+		if err := keyChange("TransactWriteItems", properties, t.nodeKey(nid)); err != nil {
+			results[nid] = err
+			continue
+		}
+		size := len(t.items)
+		results[nid] = t.update(t.NodeTableName, t.nodeKey(nid), properties, *NODE_HASH, []string{*NODE_HASH, *NODE_RANGE}, "update node %s", nid)
+		t.nodes[nid] = t.nodes[nid] || len(t.items) > size
+	}
+	return results, nil
+}
+
+// DeleteNodes reads the edges of the nodes so they are deleted along with them.  Edges already written by the
+// transaction, such as one it deletes on its own, are left to that write.
+func (t *txDriver) DeleteNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
+	edges, err := t.attached(ctx, nodes)
+	if err != nil {
+		return nil, err
+	}
+	for fid, tos := range edges {
+		for tid := range tos {
+			if _, ok := t.keys[t.itemKey(t.EdgeTableName, t.edgeKey(fid, tid))]; ok {
+				delete(tos, tid)
+			}
+		}
+	}
+	edgeResults, err := t.DeleteEdges(ctx, &edges)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(backend.NodeResults, len(*nodes))
+	for nid := range *nodes {
+		if err := detached(edgeResults, nid); err != nil {
+			results[nid] = err
+			continue
+		}
+		t.nodes[nid] = true
+		results[nid] = t.delete(t.NodeTableName, t.nodeKey(nid), "delete node %s", nid)
+	}
+	return results, nil
+}
+
+func (t *txDriver) CreateEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+	results := make(backend.EdgeResults, len(*edges))
+	for fid, tos := range *edges {
+		for tid, properties := range tos {
+			item, err := marshalProperties(properties)
+			var name string
+			if err == nil {
+				name, _, err = edgeName(properties)
+			}
+			if err == nil {
+				err = t.claim(fid, name)
+			}
+			if err != nil {
+				results.Set(fid, tid, err)
+				continue
+			}
+			for key, value := range t.edgeKey(fid, tid) {
+				item[key] = value
+			}
+			items, writes := t.createEdgeItems(fid, tid, item, name)
+			if err = t.addAll(items, writes); err == nil {
+				t.endpoints = append(t.endpoints, fid, tid)
+				t.claimed(fid, name, tid)
+			}
+			results.Set(fid, tid, err)
+		}
+	}
+	return results, nil
+}
+
+// AlterEdges reads the name of an edge whose name is changed so the item locking it can be moved along with it.
+func (t *txDriver) AlterEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+	results := make(backend.EdgeResults, len(*edges))
+	for fid, tos := range *edges {
+		for tid, properties := range tos {
+			results.Set(fid, tid, t.alterEdge(ctx, fid, tid, properties))
+		}
+	}
+	return results, nil
+}
+
+func (t *txDriver) alterEdge(ctx context.Context, fid, tid string, properties *backend.Properties) error {
+	name, renamed, err := edgeName(properties)
+	if err != nil {
+		return err
+	}
+	if renamed {
+		old, found, err := t.readName(ctx, fid, tid)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return backend.ErrNotFound
+		}
+		if old != name {
+			if err := t.claim(fid, name); err != nil {
+				return err
+			}
+			expression, names, values, err := setExpression(properties, *EDGE_HASH, *EDGE_RANGE)
+			if err != nil {
+				return err
+			}
+			items, writes := t.renameEdgeItems(fid, tid, old, name, expression, names, values)
+			if err := t.addAll(items, writes); err != nil {
+				return err
+			}
+			t.claimed(fid, name, tid)
+			return nil
+		}
+	}
+	return t.update(t.EdgeTableName, t.edgeKey(fid, tid), properties, *EDGE_HASH, []string{*EDGE_HASH, *EDGE_RANGE}, "update edge %s %s", fid, tid)
+}
+
+// DeleteEdges reads the name of each edge so the item locking it can be deleted along with it.  An edge that is
+// not there has nothing to delete.
+func (t *txDriver) DeleteEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+	results := make(backend.EdgeResults, len(*edges))
+	for fid, tos := range *edges {
+		for tid := range tos {
+			name, found, err := t.readName(ctx, fid, tid)
+			if err == nil && !found {
+				err = t.taken(t.itemKey(t.EdgeTableName, t.edgeKey(fid, tid)), fmt.Sprintf("delete edge %s %s", fid, tid))
+			} else if err == nil {
+				err = t.addAll(t.deleteEdgeItems(fid, tid, name))
+			}
+			results.Set(fid, tid, err)
+		}
+	}
+	return results, nil
+}
+
+// claim returns backend.ErrAlreadyExists if the transaction already locked the name under fid for another edge.
+// Edges without a name claim nothing.
+func (t *txDriver) claim(fid, name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := t.names[[2]string{fid, name}]; ok {
+		return backend.ErrAlreadyExists
+	}
+	return nil
+}
+
+// claimed records the name under fid as locked by the transaction for the edge to tid
+func (t *txDriver) claimed(fid, name, tid string) {
+	if name != "" {
+		t.names[[2]string{fid, name}] = tid
+	}
+}
@@ -0,0 +1,125 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+func Test_transactItems(t *testing.T) {
+	tx := &txDriver{Driver: &Driver{NodeTableName: "nodes", EdgeTableName: "edges", SourceID: "sid"}, keys: map[string]string{}, nodes: map[string]bool{}, names: map[[2]string]string{}}
+
+	// + test
+	// creating a node and linking it from an existing parent checks the parent only
+	tx.CreateNodes(context.Background(), &backend.Nodes{"1": &backend.Properties{}})
+	tx.CreateEdges(context.Background(), &backend.Edges{"root": {"1": &backend.Properties{}}})
+	tx.CreateEdges(context.Background(), &backend.Edges{"root": {"2": &backend.Properties{}}})
+	items, writes := tx.transactItems()
+	expected := []string{"put node 1", "put edge root 1", "put edge root 2", "check node root", "check node 2"}
+	descriptions := make([]string, len(writes))
+	for i, write := range writes {
+		descriptions[i] = write.description
+	}
+	if strings.Join(descriptions, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v got %v", expected, descriptions)
+	}
+	if put := items[0].Put; put == nil || *put.ConditionExpression != "attribute_not_exists(sid_nid)" {
+		t.Errorf("expected the node put to be conditional got %v", items[0])
+	}
+	check := items[3].ConditionCheck
+	if check == nil || *check.TableName != "nodes" || *check.Key[*NODE_HASH].S != "sid:root" {
+		t.Errorf("expected a check of the root node got %v", items[3])
+	}
+
+	// - test
+	// an alter that sets nothing writes nothing, so the node is still checked
+	tx = &txDriver{Driver: tx.Driver, keys: map[string]string{}, nodes: map[string]bool{}, names: map[[2]string]string{}}
+	tx.AlterNodes(context.Background(), &backend.Nodes{"1": &backend.Properties{}})
+	tx.CreateEdges(context.Background(), &backend.Edges{"1": {"2": &backend.Properties{}}})
+	if _, writes := tx.transactItems(); len(writes) != 3 || writes[1].description != "check node 1" {
+		t.Errorf("expected both ends checked got %v", writes)
+	}
+}
+
+func Test_txDuplicate(t *testing.T) {
+	tx := &txDriver{Driver: &Driver{NodeTableName: "nodes", EdgeTableName: "edges", SourceID: "sid"}, keys: map[string]string{}, nodes: map[string]bool{}, names: map[[2]string]string{}}
+
+	// + test
+	results, _ := tx.CreateNodes(context.Background(), &backend.Nodes{"1": &backend.Properties{}})
+	if results["1"] != nil {
+		t.Fatalf("expected the first write to be added got %v", results["1"])
+	}
+
+	// - test
+	properties := &backend.Properties{}
+	properties.SetString("name", "a")
+	results, _ = tx.AlterNodes(context.Background(), &backend.Nodes{"1": properties})
+	if !errors.Is(results["1"], backend.ErrUnsupported) {
+		t.Errorf("expected a second write of the node to be unsupported got %v", results["1"])
+	}
+	if len(tx.items) != 1 {
+		t.Errorf("expected the second write to be left out got %d items", len(tx.items))
+	}
+}
+
+func Test_cancelled(t *testing.T) {
+	writes := []txWrite{
+		{"put node 1", backend.ErrAlreadyExists},
+		{"put edge root 1", backend.ErrAlreadyExists},
+		{"check node root", backend.ErrConflict},
+	}
+	reason := func(code string) *dynamodb.CancellationReason {
+		return &dynamodb.CancellationReason{Code: aws.String(code)}
+	}
+
+	// + test
+	err := cancelled(&dynamodb.TransactionCanceledException{
+		CancellationReasons: []*dynamodb.CancellationReason{reason("None"), reason("None"), reason("ConditionalCheckFailed")},
+	}, writes)
+	var cerr *CancelledError
+	if !errors.Is(err, backend.ErrConflict) || !errors.As(err, &cerr) {
+		t.Fatalf("expected a conflict got %v", err)
+	}
+	if len(cerr.Reasons) != 1 || cerr.Reasons[0].Write != "check node root" {
+		t.Errorf("expected the root check as the reason got %+v", cerr.Reasons)
+	}
+
+	err = cancelled(&dynamodb.TransactionCanceledException{
+		CancellationReasons: []*dynamodb.CancellationReason{reason("ConditionalCheckFailed"), reason("None"), reason("None")},
+	}, writes)
+	if !errors.Is(err, backend.ErrAlreadyExists) {
+		t.Errorf("expected an existing node got %v", err)
+	}
+
+	err = cancelled(&dynamodb.TransactionCanceledException{
+		CancellationReasons: []*dynamodb.CancellationReason{reason("ThrottlingError"), reason("None"), reason("None")},
+	}, writes)
+	if !retryable(err) {
+		t.Errorf("expected throttling to be retried got %v", err)
+	}
+
+	// a failed condition wins over a conflict that comes before it, and a conflict over throttling
+	err = cancelled(&dynamodb.TransactionCanceledException{
+		CancellationReasons: []*dynamodb.CancellationReason{reason("TransactionConflict"), reason("ConditionalCheckFailed"), reason("None")},
+	}, writes)
+	if !errors.Is(err, backend.ErrAlreadyExists) {
+		t.Errorf("expected an existing edge got %v", err)
+	}
+	err = cancelled(&dynamodb.TransactionCanceledException{
+		CancellationReasons: []*dynamodb.CancellationReason{reason("ThrottlingError"), reason("TransactionConflict"), reason("None")},
+	}, writes)
+	if !errors.Is(err, backend.ErrConflict) {
+		t.Errorf("expected a conflict got %v", err)
+	}
+
+	// - test
+	other := errors.New("other")
+	if err := cancelled(other, writes); err != other {
+		t.Errorf("expected other errors as they are got %v", err)
+	}
+}
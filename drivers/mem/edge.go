@@ -0,0 +1,150 @@
+package mem
+
+import (
+	"context"
+
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// GetInEdges will get the edges pointing to a node, keyed by the to nid and then the from nid.  When from nids
+// are given only those edges are fetched, otherwise every edge coming into the node is, and edges that are
+// asked for but not found are reported as backend.ErrNotFound.
+func (d *Driver) GetInEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+
+	results := make(backend.EdgeResults, len(*edges))
+	err := d.read(ctx, func(g *graph) {
+		for tid, froms := range *edges {
+			if len(froms) == 0 {
+				for fid, edge := range g.in[tid] {
+					merge(edges.GetEdgeByID(tid, fid), edge)
+					results.Set(tid, fid, nil)
+				}
+				continue
+			}
+			for fid := range froms {
+				edge, ok := g.in[tid][fid]
+				if !ok {
+					results.Set(tid, fid, backend.ErrNotFound)
+					continue
+				}
+				merge(edges.GetEdgeByID(tid, fid), edge)
+				results.Set(tid, fid, nil)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetOutEdges will get the edges extending from a parent node, keyed by the from nid and then the to nid.  When
+// to nids are given only those edges are fetched, otherwise every edge going out of the parent is, and edges
+// that are asked for but not found are reported as backend.ErrNotFound.
+func (d *Driver) GetOutEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+
+	results := make(backend.EdgeResults, len(*edges))
+	err := d.read(ctx, func(g *graph) {
+		for fid, tos := range *edges {
+			if len(tos) == 0 {
+				for tid, edge := range g.out[fid] {
+					merge(edges.GetEdgeByID(fid, tid), edge)
+					results.Set(fid, tid, nil)
+				}
+				continue
+			}
+			for tid := range tos {
+				edge, ok := g.out[fid][tid]
+				if !ok {
+					results.Set(fid, tid, backend.ErrNotFound)
+					continue
+				}
+				merge(edges.GetEdgeByID(fid, tid), edge)
+				results.Set(fid, tid, nil)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// CreateEdges stores the edges with their properties.  An edge that already exists between the same two nids,
+// or whose name is already taken by another edge out of the same nid, is left out and reported as
+// backend.ErrAlreadyExists.
+func (d *Driver) CreateEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+
+	results := make(backend.EdgeResults, len(*edges))
+	err := d.write(ctx, func(g *graph) {
+		for fid, tos := range *edges {
+			for tid, properties := range tos {
+				if _, ok := g.out[fid][tid]; ok {
+					results.Set(fid, tid, backend.ErrAlreadyExists)
+					continue
+				}
+				if name, err := properties.GetString("name"); err == nil && g.child(fid, name) != "" {
+					results.Set(fid, tid, backend.ErrAlreadyExists)
+					continue
+				}
+				g.setEdge(fid, tid, properties.Clone())
+				results.Set(fid, tid, nil)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// AlterEdges sets the given properties on existing edges and fills in the updated edges.  Edges that do not
+// exist are left alone and reported as backend.ErrNotFound, and edges renamed to a name already taken by another
+// edge out of the same nid are left alone and reported as backend.ErrAlreadyExists.
+func (d *Driver) AlterEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+
+	results := make(backend.EdgeResults, len(*edges))
+	err := d.write(ctx, func(g *graph) {
+		for fid, tos := range *edges {
+			for tid, properties := range tos {
+				edge, ok := g.out[fid][tid]
+				if !ok {
+					results.Set(fid, tid, backend.ErrNotFound)
+					continue
+				}
+				if name, err := properties.GetString("name"); err == nil {
+					if child := g.child(fid, name); child != "" && child != tid {
+						results.Set(fid, tid, backend.ErrAlreadyExists)
+						continue
+					}
+				}
+				merge(edge, properties)
+				merge(properties, edge)
+				results.Set(fid, tid, nil)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// DeleteEdges removes the edges between the given from and to nids.  Deleting an edge that does not exist is
+// not an error.
+func (d *Driver) DeleteEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+
+	results := make(backend.EdgeResults, len(*edges))
+	err := d.write(ctx, func(g *graph) {
+		for fid, tos := range *edges {
+			for tid := range tos {
+				g.deleteEdge(fid, tid)
+				results.Set(fid, tid, nil)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,188 @@
+package mem
+
+/*
+An in-memory backend for unit tests and local development.  It keeps nodes and edges in maps, shared by every
+driver in the process the same way a ddb table is shared, and isolates them by source id just like ddb does with
+its sid prefixed keys.  Nothing is persisted.
+*/
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// Constants for the package
+var (
+	PackageName = "mem"
+
+	// the sid used when none is given in the config
+	DefaultSourceID = "default"
+)
+
+// will register this package as a know backend
+func init() {
+	log.Printf("Registering %s as a backend", PackageName)
+	backend.RegisterBackend(PackageName, newDriver)
+}
+
+// graph holds the nodes and edges of a single source id
+type graph struct {
+	nodes map[string]*backend.Properties
+	// out maps a from nid to a to nid to the edge, in is the same edges keyed the other way around
+	out map[string]map[string]*backend.Properties
+	in  map[string]map[string]*backend.Properties
+}
+
+func newGraph() *graph {
+	return &graph{
+		nodes: make(map[string]*backend.Properties),
+		out:   make(map[string]map[string]*backend.Properties),
+		in:    make(map[string]map[string]*backend.Properties),
+	}
+}
+
+func (g *graph) setEdge(fid, tid string, edge *backend.Properties) {
+	if g.out[fid] == nil {
+		g.out[fid] = make(map[string]*backend.Properties)
+	}
+	if g.in[tid] == nil {
+		g.in[tid] = make(map[string]*backend.Properties)
+	}
+	g.out[fid][tid] = edge
+	g.in[tid][fid] = edge
+}
+
+// child returns the nid the edge out of fid with the given name points to, or an empty nid if there is none.  If
+// more than one edge has the name the smallest nid is returned so the lookup is deterministic.
+func (g *graph) child(fid, name string) string {
+	var nid string
+	for tid, edge := range g.out[fid] {
+		if n, err := edge.GetString("name"); err != nil || n != name {
+			continue
+		}
+		if nid == "" || tid < nid {
+			nid = tid
+		}
+	}
+	return nid
+}
+
+// clone returns a deep copy of the graph, with each edge still shared between out and in
+func (g *graph) clone() *graph {
+	c := newGraph()
+	for nid, node := range g.nodes {
+		c.nodes[nid] = node.Clone()
+	}
+	for fid, tos := range g.out {
+		for tid, edge := range tos {
+			c.setEdge(fid, tid, edge.Clone())
+		}
+	}
+	return c
+}
+
+func (g *graph) deleteEdge(fid, tid string) {
+	delete(g.out[fid], tid)
+	if len(g.out[fid]) == 0 {
+		delete(g.out, fid)
+	}
+	delete(g.in[tid], fid)
+	if len(g.in[tid]) == 0 {
+		delete(g.in, tid)
+	}
+}
+
+// store holds the graphs of every source id
+type store struct {
+	sync.RWMutex
+	graphs map[string]*graph
+}
+
+// graph returns the graph of the source id, creating it when it does not exist yet.  The store must be locked
+// for writing if the graph may not exist.
+func (s *store) graph(sid string) *graph {
+	g, ok := s.graphs[sid]
+	if !ok {
+		g = newGraph()
+		s.graphs[sid] = g
+	}
+	return g
+}
+
+// the store shared by all the drivers in the process
+var shared = &store{graphs: make(map[string]*graph)}
+
+// Driver struct to house the source id and the store it reads and writes
+type Driver struct {
+	SourceID string
+	store    *store
+	// tx is the copy of the graph a transaction works on, see WithTx
+	tx *graph
+}
+
+// creates a new driver on the shared store for the source id in the config
+func newDriver(c *backend.Config) (backend.Graph, error) {
+	sid := DefaultSourceID
+	if c.HasKey("sid") {
+		sid = c.StringKey("sid")
+	}
+	return &Driver{
+		SourceID: sid,
+		store:    shared,
+	}, nil
+}
+
+// read calls fn with the graph of the driver's source id locked for reading, or the graph of its transaction
+func (d *Driver) read(ctx context.Context, fn func(*graph)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d.tx != nil {
+		fn(d.tx)
+		return nil
+	}
+	d.store.RLock()
+	defer d.store.RUnlock()
+	g, ok := d.store.graphs[d.SourceID]
+	if !ok {
+		g = newGraph()
+	}
+	fn(g)
+	return nil
+}
+
+// write calls fn with the graph of the driver's source id locked for writing, or the graph of its transaction
+func (d *Driver) write(ctx context.Context, fn func(*graph)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d.tx != nil {
+		fn(d.tx)
+		return nil
+	}
+	d.store.Lock()
+	defer d.store.Unlock()
+	fn(d.store.graph(d.SourceID))
+	return nil
+}
+
+// merge copies the properties from src into dst, leaving out the given keys.  A property given as nil is removed
+// from dst.
+func merge(dst, src *backend.Properties, skip ...string) {
+outer:
+	for key, property := range *src.Clone() {
+		for _, s := range skip {
+			if key == s {
+				continue outer
+			}
+		}
+		if property == nil {
+			delete(*dst, key)
+			continue
+		}
+		(*dst)[key] = property
+	}
+}
@@ -0,0 +1,12 @@
+package mem
+
+import (
+	"testing"
+
+	"github.com/sir-wiggles/bcfs/backend"
+	"github.com/sir-wiggles/bcfs/backend/graphtest"
+)
+
+func Test_Graph(t *testing.T) {
+	graphtest.Run(t, newDriver, backend.Config{"name": PackageName})
+}
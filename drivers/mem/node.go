@@ -0,0 +1,98 @@
+package mem
+
+import (
+	"context"
+
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// GetNodes fills in the properties of the nodes given their IDs.  Nodes that are not found are left as is and
+// reported as backend.ErrNotFound.
+func (d *Driver) GetNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
+
+	results := make(backend.NodeResults, len(*nodes))
+	err := d.read(ctx, func(g *graph) {
+		for nid := range *nodes {
+			node, ok := g.nodes[nid]
+			if !ok {
+				results[nid] = backend.ErrNotFound
+				continue
+			}
+			merge(nodes.GetNodeByID(nid), node)
+			results[nid] = nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// CreateNodes stores the nodes with their properties.  A node whose nid is already in use is left unchanged and
+// reported as backend.ErrAlreadyExists.
+func (d *Driver) CreateNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
+
+	results := make(backend.NodeResults, len(*nodes))
+	err := d.write(ctx, func(g *graph) {
+		for nid, properties := range *nodes {
+			if _, ok := g.nodes[nid]; ok {
+				results[nid] = backend.ErrAlreadyExists
+				continue
+			}
+			node := properties.Clone()
+			node.SetString("nid", nid)
+			g.nodes[nid] = node
+			results[nid] = nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// AlterNodes sets the given properties on existing nodes and fills in the updated nodes.  The nid can not be
+// altered.  Nodes that do not exist are left alone and reported as backend.ErrNotFound.
+func (d *Driver) AlterNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
+
+	results := make(backend.NodeResults, len(*nodes))
+	err := d.write(ctx, func(g *graph) {
+		for nid, properties := range *nodes {
+			node, ok := g.nodes[nid]
+			if !ok {
+				results[nid] = backend.ErrNotFound
+				continue
+			}
+			merge(node, properties, "nid")
+			merge(properties, node)
+			results[nid] = nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// DeleteNodes removes the given nodes along with the edges going out of and coming into them, the same as ddb and
+// neo do.  Deleting a node that does not exist is not an error.
+func (d *Driver) DeleteNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
+
+	results := make(backend.NodeResults, len(*nodes))
+	err := d.write(ctx, func(g *graph) {
+		for nid := range *nodes {
+			for tid := range g.out[nid] {
+				g.deleteEdge(nid, tid)
+			}
+			for fid := range g.in[nid] {
+				g.deleteEdge(fid, nid)
+			}
+			delete(g.nodes, nid)
+			results[nid] = nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
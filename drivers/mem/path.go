@@ -0,0 +1,32 @@
+package mem
+
+import (
+	"context"
+
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// GetPath resolves a slash separated path starting at the root nid, following the edge with the matching name
+// at each level.
+func (d *Driver) GetPath(ctx context.Context, root string, path string) (*backend.Path, error) {
+
+	p, err := backend.ResolvePath(ctx, root, path, d.lookupEdgeByName)
+	if err != nil {
+		return nil, err
+	}
+	return p, p.FillNodes(ctx, d.GetNodes)
+}
+
+// lookupEdgeByName finds the edge going out of the parent that has the given name and returns the nid it points
+// to along with the edge properties, or an empty nid if there is no such edge.
+func (d *Driver) lookupEdgeByName(ctx context.Context, parent, name string) (string, *backend.Properties, error) {
+
+	var nid string
+	var properties *backend.Properties
+	err := d.read(ctx, func(g *graph) {
+		if nid = g.child(parent, name); nid != "" {
+			properties = g.out[parent][nid].Clone()
+		}
+	})
+	return nid, properties, err
+}
@@ -0,0 +1,32 @@
+package mem
+
+import (
+	"context"
+
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// WithTx calls fn with a driver working on a copy of the graph of the source id, which replaces the graph once
+// fn returns nil and is thrown away otherwise.  The store stays locked for writing the whole time so
+// transactions, and every other call, wait on each other.
+func (d *Driver) WithTx(ctx context.Context, fn func(backend.Graph) error) error {
+	if d.tx != nil {
+		return fn(d)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	g := d.store.graph(d.SourceID).clone()
+	if err := fn(&Driver{SourceID: d.SourceID, store: d.store, tx: g}); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.store.graphs[d.SourceID] = g
+	return nil
+}
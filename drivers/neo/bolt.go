@@ -0,0 +1,258 @@
+package neo
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmcvetta/neoism"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j/config"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// Defaults of the bolt transport used when the config leaves them out
+var (
+	DefaultBoltPort = 7687
+
+	// the most connections kept open to each server
+	DefaultMaxPoolSize = 100
+	// how long to wait for a connection to be established, in seconds
+	DefaultConnectTimeout = 5
+	// how long to wait for a connection from the pool when all of them are in use, in seconds
+	DefaultAcquireTimeout = 60
+	// how long a connection is kept around before it is closed and replaced, in seconds
+	DefaultMaxConnectionLifetime = 3600
+)
+
+// boltTransport runs statements over the Bolt protocol.  The neo4j driver keeps a pool of connections that is
+// shared by every call, each call getting its own session.
+type boltTransport struct {
+	driver neo4j.DriverWithContext
+	// database is the name of the database to use, empty for the default database of the server
+	database string
+}
+
+// newBoltTransport connects to the server given by the config.  The scheme of the URI decides whether TLS is
+// used and whether the server certificate is verified: "bolt" talks to a single server and "neo4j" routes
+// through a cluster.
+func newBoltTransport(c *backend.Config) (*boltTransport, error) {
+
+	scheme := "bolt"
+	if boolKey(c, "routing", false) {
+		scheme = "neo4j"
+	}
+
+	var tlsConfig *tls.Config
+	if boolKey(c, "tls", false) {
+		scheme += "+s"
+		if boolKey(c, "tls-skip-verify", false) {
+			// self signed certificates are accepted without a CA
+			scheme = strings.TrimSuffix(scheme, "+s") + "+ssc"
+		}
+		if c.HasKey("tls-ca-file") && c.StringKey("tls-ca-file") != "" {
+			pem, err := os.ReadFile(c.StringKey("tls-ca-file"))
+			if err != nil {
+				return nil, err
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("No certificates found in tls-ca-file %s", c.StringKey("tls-ca-file"))
+			}
+			tlsConfig = &tls.Config{RootCAs: pool}
+		}
+	}
+
+	port := DefaultBoltPort
+	if c.HasKey("port") {
+		port = c.IntKey("port")
+	}
+	uri := fmt.Sprintf("%s://%s:%d", scheme, c.StringKey("host"), port)
+
+	maxPoolSize := intKey(c, "max-pool-size", DefaultMaxPoolSize)
+	if maxPoolSize < 1 {
+		return nil, fmt.Errorf("Invalid max-pool-size %d", maxPoolSize)
+	}
+
+	driver, err := neo4j.NewDriverWithContext(
+		uri,
+		neo4j.BasicAuth(c.StringKey("user"), c.StringKey("password"), ""),
+		func(cfg *config.Config) {
+			cfg.MaxConnectionPoolSize = maxPoolSize
+			cfg.SocketConnectTimeout = time.Duration(intKey(c, "connect-timeout", DefaultConnectTimeout)) * time.Second
+			cfg.ConnectionAcquisitionTimeout = time.Duration(intKey(c, "acquire-timeout", DefaultAcquireTimeout)) * time.Second
+			cfg.MaxConnectionLifetime = time.Duration(intKey(c, "max-connection-lifetime", DefaultMaxConnectionLifetime)) * time.Second
+			cfg.TlsConfig = tlsConfig
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	database := ""
+	if c.HasKey("database") {
+		database = c.StringKey("database")
+	}
+	return &boltTransport{driver: driver, database: database}, nil
+}
+
+// begin opens a session and begins a transaction in it.  The session is closed once the transaction is
+// committed or rolled back.
+func (t *boltTransport) begin(ctx context.Context) (transaction, error) {
+	session := t.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: t.database})
+	tx, err := session.BeginTransaction(ctx)
+	if err != nil {
+		log.Debugf("Begin Tx error: %s", err.Error())
+		t.closeSession(session)
+		return nil, err
+	}
+	return &boltTransaction{transport: t, session: session, tx: tx}, nil
+}
+
+func (t *boltTransport) closeSession(session neo4j.SessionWithContext) {
+	if err := session.Close(context.Background()); err != nil {
+		log.Debugf("Close session error: %s", err.Error())
+	}
+}
+
+// close closes every connection in the pool
+func (t *boltTransport) close(ctx context.Context) error {
+	return t.driver.Close(ctx)
+}
+
+// boltTransaction is an explicit transaction in a session of its own
+type boltTransaction struct {
+	transport *boltTransport
+	session   neo4j.SessionWithContext
+	tx        neo4j.ExplicitTransaction
+}
+
+func (t *boltTransaction) run(ctx context.Context, statements []*neoism.CypherQuery) error {
+	for _, q := range statements {
+		result, err := t.tx.Run(ctx, q.Statement, boltParameters(q.Parameters))
+		if err != nil {
+			return err
+		}
+		records, err := result.Collect(ctx)
+		if err != nil {
+			return err
+		}
+		if err := decode(records, q.Result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commit commits the transaction, or rolls it back if the caller has given up in the meantime
+func (t *boltTransaction) commit(ctx context.Context) error {
+	defer t.transport.closeSession(t.session)
+	if err := ctx.Err(); err != nil {
+		if rerr := t.tx.Rollback(context.Background()); rerr != nil {
+			log.Debugf("Rollback Tx error: %s", rerr.Error())
+		}
+		return err
+	}
+	if err := t.tx.Commit(ctx); err != nil {
+		log.Debugf("Commit Tx error: %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// rollback rolls back the transaction even when the context is done, which is usually why it is rolled back
+func (t *boltTransaction) rollback(ctx context.Context) error {
+	defer t.transport.closeSession(t.session)
+	return t.tx.Rollback(context.Background())
+}
+
+// boltParameters copies the parameters of a statement turning neoism.Props into plain maps, which is all the
+// bolt driver knows how to send
+func boltParameters(parameters map[string]interface{}) map[string]interface{} {
+	if parameters == nil {
+		return nil
+	}
+	return boltParameter(parameters).(map[string]interface{})
+}
+
+func boltParameter(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case neoism.Props:
+		return boltParameter(map[string]interface{}(vv))
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, e := range vv {
+			m[k] = boltParameter(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(vv))
+		for i, e := range vv {
+			l[i] = boltParameter(e)
+		}
+		return l
+	}
+	return v
+}
+
+// decode appends a row to result for each record.  result is a pointer to a slice of structs and the columns of
+// a record go to the fields whose json tag names them, the same as neoism decodes the rows it gets over HTTP.
+// Nodes and relationships are decoded as their properties.
+func decode(records []*neo4j.Record, result interface{}) error {
+	if result == nil {
+		return nil
+	}
+	slice := reflect.ValueOf(result)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice || slice.Elem().Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Can not decode rows into %T", result)
+	}
+	slice = slice.Elem()
+	rowType := slice.Type().Elem()
+
+	for _, record := range records {
+		row := reflect.New(rowType).Elem()
+		for i := 0; i < rowType.NumField(); i++ {
+			field := rowType.Field(i)
+			column := strings.Split(field.Tag.Get("json"), ",")[0]
+			v, ok := record.Get(column)
+			if !ok || v == nil {
+				continue
+			}
+			switch vv := v.(type) {
+			case neo4j.Node:
+				v = vv.Props
+			case neo4j.Relationship:
+				v = vv.Props
+			}
+			value := reflect.ValueOf(v)
+			if !value.Type().AssignableTo(field.Type) {
+				return fmt.Errorf("Can not decode column %s of type %T into %s", column, v, field.Type)
+			}
+			row.Field(i).Set(value)
+		}
+		slice.Set(reflect.Append(slice, row))
+	}
+	return nil
+}
+
+// intKey returns the int value of key from the config or def when it is not given
+func intKey(c *backend.Config, key string, def int) int {
+	if c.HasKey(key) {
+		return c.IntKey(key)
+	}
+	return def
+}
+
+// boolKey returns the bool value of key from the config or def when it is not given
+func boolKey(c *backend.Config, key string, def bool) bool {
+	if c.HasKey(key) {
+		return c.BoolKey(key)
+	}
+	return def
+}
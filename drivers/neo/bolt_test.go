@@ -0,0 +1,88 @@
+package neo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jmcvetta/neoism"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+func Test_decode(t *testing.T) {
+	records := []*neo4j.Record{
+		{
+			Keys:   []string{"nid", "n", "created"},
+			Values: []interface{}{"1", neo4j.Node{Props: map[string]interface{}{"name": "a"}}, true},
+		},
+		{
+			Keys:   []string{"from", "to", "n"},
+			Values: []interface{}{"1", "2", neo4j.Relationship{Props: map[string]interface{}{"name": "b"}}},
+		},
+	}
+
+	// + test
+	rows := &[]neoResponse{}
+	if err := decode(records, rows); err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := []neoResponse{
+		{Nid: "1", Data: map[string]interface{}{"name": "a"}, Created: true},
+		{From: "1", To: "2", Data: map[string]interface{}{"name": "b"}},
+	}
+	if !reflect.DeepEqual(*rows, expected) {
+		t.Errorf("expected %v got %v", expected, *rows)
+	}
+
+	// - test
+	if err := decode(records, &[]nameResponse{}); err != nil {
+		t.Errorf("missing columns should be left empty: %s", err.Error())
+	}
+	bad := []*neo4j.Record{{Keys: []string{"nid"}, Values: []interface{}{int64(1)}}}
+	if err := decode(bad, &[]neoResponse{}); err == nil {
+		t.Error("an int should not be decoded into a string")
+	}
+	if err := decode(records, &neoResponse{}); err == nil {
+		t.Error("rows should only be decoded into a slice")
+	}
+}
+
+func Test_boltParameters(t *testing.T) {
+	parameters := map[string]interface{}{
+		"rows": []interface{}{neoism.Props{"from": "1", "to": "2"}},
+	}
+	expected := map[string]interface{}{
+		"rows": []interface{}{map[string]interface{}{"from": "1", "to": "2"}},
+	}
+	if p := boltParameters(parameters); !reflect.DeepEqual(p, expected) {
+		t.Errorf("expected %v got %v", expected, p)
+	}
+}
+
+func Test_newDriverTransport(t *testing.T) {
+	// + test
+	// connections are only made once the pool is used
+	g, err := newDriver(&backend.Config{
+		"transport":       TransportBolt,
+		"user":            "neo4j",
+		"password":        "test",
+		"host":            "localhost",
+		"tls":             true,
+		"tls-skip-verify": true,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := g.(backend.Closer).Close(context.Background()); err != nil {
+		t.Error(err.Error())
+	}
+
+	// - test
+	if _, err := newDriver(&backend.Config{"transport": "smoke-signals"}); err == nil {
+		t.Error("an unknown transport should be an error")
+	}
+	if _, err := newDriver(&backend.Config{"transport": TransportBolt, "host": "localhost", "max-pool-size": 0}); err == nil {
+		t.Error("an empty pool should be an error")
+	}
+}
@@ -0,0 +1,76 @@
+package neo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/jmcvetta/neoism"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// newError maps an error returned from neo for the operation op into a backend error.  Context errors and
+// errors that are already backend errors are returned as is.
+func newError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var berr *backend.Error
+	if errors.As(err, &berr) {
+		return err
+	}
+	return backend.NewError(PackageName, op, errorKind(err), err)
+}
+
+// errorKind classifies a neo error into one of the backend error kinds, or nil if it is none of them
+func errorKind(err error) error {
+	if errors.Is(err, neoism.ErrNotFound) {
+		return backend.ErrNotFound
+	}
+
+	var qerr neoism.TxQueryError
+	if errors.As(err, &qerr) {
+		for _, e := range qerr.Errors {
+			if kind := codeKind(e.Code); kind != nil {
+				return kind
+			}
+		}
+		return nil
+	}
+
+	var berr *neo4j.Neo4jError
+	if errors.As(err, &berr) {
+		return codeKind(berr.Code)
+	}
+
+	// failing to reach neo shows up as a connectivity error over bolt and a network error over HTTP
+	var cerr *neo4j.ConnectivityError
+	if errors.As(err, &cerr) {
+		return backend.ErrUnavailable
+	}
+	var nerr net.Error
+	if errors.As(err, &nerr) {
+		return backend.ErrUnavailable
+	}
+	return nil
+}
+
+// codeKind classifies the status code of a neo error, which is the same over either transport
+func codeKind(code string) error {
+	switch {
+	// deleting a node that still has relationships and breaking a uniqueness constraint both end up here
+	case code == "Neo.ClientError.Schema.ConstraintValidationFailed":
+		return backend.ErrConflict
+	case code == "Neo.TransientError.Transaction.DeadlockDetected":
+		return backend.ErrConflict
+	case strings.HasPrefix(code, "Neo.TransientError."),
+		strings.HasPrefix(code, "Neo.DatabaseError."):
+		return backend.ErrUnavailable
+	}
+	return nil
+}
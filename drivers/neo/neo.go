@@ -1,9 +1,10 @@
 package neo
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"strconv"
+	"sort"
+	"sync"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/jmcvetta/neoism"
@@ -13,8 +14,17 @@ import (
 // Constants for the package
 var (
 	PackageName = "neo"
+
+	// the sid used when none is given in the config
+	DefaultSourceID = "default"
+
+	// the most rows sent in a single UNWIND statement when none is given in the config
+	DefaultBatchSize = 1000
 )
 
+// edgeType is the type of the relationships edges are stored as
+const edgeType = "EDGE"
+
 // will register this package as a know backend
 func init() {
 	log.Infof("Registering %s as a backend", PackageName)
@@ -23,320 +33,629 @@ func init() {
 
 // Driver struct to house all relative information about DB connections
 type Driver struct {
-	Connection  *neoism.Database
-	Transaction *neoism.Tx
-	sid         string
+	// Connection is only set when the driver talks to neo over the REST transport
+	Connection *neoism.Database
+	// transport is joined to the transaction in progress for the driver handed to the function given to WithTx
+	transport transport
+	sid       string
+	// label is the sid escaped for use as the label of every node
+	label string
+	// batchSize is the most rows sent in a single UNWIND statement
+	batchSize int
+
+	// autoSchema provisions the schema of the sid on first use, see ensureSchema
+	autoSchema  bool
+	schemaMu    sync.Mutex
+	schemaReady bool
 }
 
 // creates a new driver with the unique set of config options specified in the config file
 func newDriver(c *backend.Config) (backend.Graph, error) {
 
-	url := fmt.Sprintf(
-		"http://%s:%s@%s:%d",
-		c.StringKey("user"),
-		c.StringKey("password"),
-		c.StringKey("host"),
-		c.IntKey("port"),
-	)
+	// nodes are labelled with the source id to keep each source's graph apart
+	sid := DefaultSourceID
+	if c.HasKey("sid") {
+		sid = c.StringKey("sid")
+	}
+
+	label, err := identifier(sid)
+	if err != nil {
+		return nil, backend.NewError(PackageName, "newDriver", nil, err)
+	}
+
+	batchSize := DefaultBatchSize
+	if c.HasKey("batch-size") {
+		batchSize = c.IntKey("batch-size")
+	}
+	if batchSize < 1 {
+		return nil, backend.NewError(PackageName, "newDriver", nil, fmt.Errorf("Invalid batch-size %d", batchSize))
+	}
+
+	autoSchema := true
+	if c.HasKey("ensure-schema") {
+		autoSchema = c.BoolKey("ensure-schema")
+	}
+
+	d := &Driver{
+		sid:        sid,
+		label:      label,
+		batchSize:  batchSize,
+		autoSchema: autoSchema,
+	}
 
-	//	db, err := sql.Open("neo4j-cypher", url)
-	db, err := neoism.Connect(url)
-	return &Driver{
-		Connection: db,
-	}, err
+	name := TransportREST
+	if c.HasKey("transport") {
+		name = c.StringKey("transport")
+	}
+	switch name {
+	case TransportREST:
+		url := fmt.Sprintf(
+			"http://%s:%s@%s:%d",
+			c.StringKey("user"),
+			c.StringKey("password"),
+			c.StringKey("host"),
+			c.IntKey("port"),
+		)
+
+		//	db, err := sql.Open("neo4j-cypher", url)
+		db, err := neoism.Connect(url)
+		if err != nil {
+			return nil, backend.NewError(PackageName, "newDriver", backend.ErrUnavailable, err)
+		}
+		d.Connection = db
+		d.transport = &restTransport{db: db}
+	case TransportBolt:
+		t, err := newBoltTransport(c)
+		if err != nil {
+			return nil, backend.NewError(PackageName, "newDriver", nil, err)
+		}
+		d.transport = t
+	default:
+		return nil, backend.NewError(PackageName, "newDriver", nil, fmt.Errorf("Unknown transport %s", name))
+	}
+	return d, nil
 }
 
 type neoResponse struct {
+	Nid     string                 `json:"nid"`
+	From    string                 `json:"from"`
+	To      string                 `json:"to"`
 	Data    map[string]interface{} `json:"n"`
 	Created bool                   `json:"created"`
 }
 
-// GetNodes returns nodes from the backend storage given their IDs
-func (d *Driver) GetNodes(nodes *backend.Nodes) (*backend.Nodes, error) {
+// run executes the statements in a single transaction, making sure the schema of the sid is in place first
+func (d *Driver) run(ctx context.Context, op string, statements []*neoism.CypherQuery) error {
+	if err := d.ensureSchema(ctx); err != nil {
+		return err
+	}
+	return d.exec(ctx, op, statements)
+}
 
-	statements := make([]*neoism.CypherQuery, 0, len(*nodes))
-	responses := make([]*[]neoResponse, 0, len(*nodes))
+// exec executes the statements in a single transaction over the transport of the driver.  The transaction is
+// rolled back if any of the statements fail.
+func (d *Driver) exec(ctx context.Context, op string, statements []*neoism.CypherQuery) error {
 
-	for nid := range *nodes {
-		// Each statment will get a res struct to house the returned node from Neo
-		r := &[]neoResponse{}
-		q := &neoism.CypherQuery{
-			// we need the back ticks for the label because some may start with a number
-			// and cypher requires that we back tick those.
-			Statement: fmt.Sprintf("MATCH (n:`%s` {nid:'%s'}) RETURN n;", d.sid, nid),
-			Result:    r,
+	tx, err := d.transport.begin(ctx)
+	if err != nil {
+		return newError(op, err)
+	}
+	if err := tx.run(ctx, statements); err != nil {
+		if rerr := tx.rollback(ctx); rerr != nil {
+			log.Debugf("Rollback Tx error: %s", rerr.Error())
 		}
+		return newError(op, err)
+	}
+	if err := tx.commit(ctx); err != nil {
+		return newError(op, err)
+	}
+	return nil
+}
 
-		statements = append(statements, q)
-		responses = append(responses, r)
+// WithTx calls fn with a driver whose statements all run in one transaction, which is committed when fn returns
+// nil and rolled back otherwise.  The schema is set up beforehand since neo does not allow changing the schema
+// in a transaction that writes data.
+func (d *Driver) WithTx(ctx context.Context, fn func(backend.Graph) error) error {
 
-		log.Debug(q)
+	if err := d.ensureSchema(ctx); err != nil {
+		return err
 	}
-
-	tx, err := d.Connection.Begin(statements)
+	tx, err := d.transport.begin(ctx)
 	if err != nil {
-		log.Debugf("Begin Tx error: %s", err.Error())
-		return nil, err
+		return newError("WithTx", err)
 	}
 
-	err = tx.Commit()
+	err = fn(&Driver{
+		Connection: d.Connection,
+		transport:  &joinedTransport{tx: tx},
+		sid:        d.sid,
+		label:      d.label,
+		batchSize:  d.batchSize,
+	})
 	if err != nil {
-		log.Debugf("Commit Tx error: %s", err.Error())
+		if rerr := tx.rollback(ctx); rerr != nil {
+			log.Debugf("Rollback Tx error: %s", rerr.Error())
+		}
+		return err
+	}
+	return newError("WithTx", tx.commit(ctx))
+}
+
+// Close releases the connections held by the driver
+func (d *Driver) Close(ctx context.Context) error {
+	return newError("Close", d.transport.close(ctx))
+}
+
+// GetNodes fills in the properties of the nodes given their IDs.  Nodes that are not found are left as is and
+// reported as backend.ErrNotFound.  The nids are looked up batchSize at a time with one UNWIND statement each.
+func (d *Driver) GetNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
+
+	rows := make([]interface{}, 0, len(*nodes))
+	for nid := range *nodes {
+		rows = append(rows, nid)
+	}
+
+	statements, responses := d.unwind(
+		fmt.Sprintf("UNWIND $rows AS nid MATCH (n:%s {nid:nid}) RETURN nid, n;", d.label),
+		rows,
+	)
+	if err := d.run(ctx, "GetNodes", statements); err != nil {
 		return nil, err
 	}
 
 	// Translate the nodes into a valid backend node
-	bn := make(backend.Nodes, len(*nodes))
+	results := make(backend.NodeResults, len(*nodes))
+	for nid := range *nodes {
+		results[nid] = backend.ErrNotFound
+	}
 	for _, r := range responses {
-		if len(*r) == 0 {
-			continue
-		}
-		resp := (*r)[0].Data
-		if resp == nil {
-			continue
+		for _, row := range *r {
+			(*nodes)[row.Nid] = toProperties(row.Data)
+			results[row.Nid] = nil
 		}
-		bn[resp["nid"].(string)] = resp
 	}
 
-	return &bn, nil
+	return results, nil
 }
 
-// CreateNodes will create a node in the graph and return the newly created node
-// If the node already exists, then the existing node will remain unchanged
-func (d *Driver) CreateNodes(nodes *backend.Nodes) (*backend.Nodes, error) {
+// CreateNodes will create a node in the graph and fill in the newly created node
+// If the node already exists, then the existing node will remain unchanged and
+// is reported as backend.ErrAlreadyExists
+func (d *Driver) CreateNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
 
-	statements := make([]*neoism.CypherQuery, 0, len(*nodes))
-	responses := make([]*[]neoResponse, 0, len(*nodes))
-	createQuery := `MERGE (n:` + "`%s`" + ` {nid:'%s'})
-	ON CREATE SET n.__created__ = true, %s
-	WITH n, n.__created__ as created
+	createQuery := `UNWIND $rows AS row
+	MERGE (n:%s {nid:row.nid})
+	ON CREATE SET n.__created__ = true%s
+	WITH row, n, n.__created__ as created
 	REMOVE n.__created__
-	RETURN n, created;`
-	for nid, properties := range *nodes {
-		buffer := bytes.NewBufferString("")
-		for k, v := range properties {
-			// nid is the index and should never be altered
-			if k == "nid" {
-				nid = v.(string)
+	RETURN row.nid AS nid, n, created;`
+
+	results := make(backend.NodeResults, len(*nodes))
+	statements, responses := d.unwindNodes(nodes, results, func(set string) string {
+		if set != "" {
+			set = ", " + set
+		}
+		return fmt.Sprintf(createQuery, d.label, set)
+	})
+	if err := d.run(ctx, "CreateNodes", statements); err != nil {
+		return nil, err
+	}
+
+	// Translate the nodes into a valid backend node
+	for _, r := range responses {
+		for _, row := range *r {
+			(*nodes)[row.Nid] = toProperties(row.Data)
+			if !row.Created {
+				results[row.Nid] = backend.ErrAlreadyExists
 				continue
 			}
-			switch v.(type) {
-			case int, int32, int64, float32, float64:
-				buffer.WriteString(fmt.Sprintf("n.%s=%d", k, v))
-			case string:
-				buffer.WriteString(fmt.Sprintf("n.%s='%s'", k, v))
-			}
-			buffer.WriteString(",")
+			results[row.Nid] = nil
 		}
-		buffer.Truncate(buffer.Len() - 1)
-		buffer.WriteString("")
+	}
 
-		// Each statment will get a res struct to house the returned node from Neo
-		r := &[]neoResponse{}
-		q := &neoism.CypherQuery{
-			// we need the back ticks for the label because some may start with a number
-			// and cypher requires that we back tick those.
-			Statement: fmt.Sprintf(createQuery, d.sid, nid, buffer.String()),
-			Result:    r,
-		}
+	return results, nil
+}
 
-		statements = append(statements, q)
-		responses = append(responses, r)
-		log.Debug(q)
-	}
+// AlterNodes will update the specified node with the parameters given.  If
+// no node was found then nothing will happen and it is reported as
+// backend.ErrNotFound.
+func (d *Driver) AlterNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
 
-	tx, err := d.Connection.Begin(statements)
-	if err != nil {
-		log.Debugf("Begin Tx error: %s", err.Error())
-		return nil, err
-	}
+	alterQuery := `UNWIND $rows AS row
+	MATCH (n:%s {nid:row.nid})
+	SET %s
+	RETURN row.nid AS nid, n;`
 
-	err = tx.Commit()
-	if err != nil {
-		log.Debugf("Commit Tx error: %s", err.Error())
+	results := make(backend.NodeResults, len(*nodes))
+	statements, responses := d.unwindNodes(nodes, results, func(set string) string {
+		if set == "" {
+			// nothing to set, only check the nodes exist
+			return fmt.Sprintf("UNWIND $rows AS row MATCH (n:%s {nid:row.nid}) RETURN row.nid AS nid, n;", d.label)
+		}
+		return fmt.Sprintf(alterQuery, d.label, set)
+	})
+	if err := d.run(ctx, "AlterNodes", statements); err != nil {
 		return nil, err
 	}
 
 	// Translate the nodes into a valid backend node
-	bn := make(backend.Nodes, len(*nodes))
 	for _, r := range responses {
-		resp := (*r)[0].Data
-		if resp == nil {
-			continue
+		for _, row := range *r {
+			(*nodes)[row.Nid] = toProperties(row.Data)
+			results[row.Nid] = nil
 		}
-		bn[resp["nid"].(string)] = resp
 	}
 
-	return &bn, nil
+	return results, nil
 }
 
-// AlterNodes will update the specified node with the parameters given.  If
-// no node was found then nothing will happen and a null node will be returned.
-func (d *Driver) AlterNodes(nodes *backend.Nodes) (*backend.Nodes, error) {
-	statements := make([]*neoism.CypherQuery, 0, len(*nodes))
-	responses := make([]*[]neoResponse, 0, len(*nodes))
-	createQuery := `MATCH (n:` + "`%s`" + ` {nid:'%s'})
-	SET %s
-	RETURN n;`
+// DeleteNodes will delete the given nodes from the graph along with all of
+// their relationships, the same as ddb and mem do.
+func (d *Driver) DeleteNodes(ctx context.Context, nodes *backend.Nodes) (backend.NodeResults, error) {
+
+	rows := make([]interface{}, 0, len(*nodes))
+	for nid := range *nodes {
+		rows = append(rows, nid)
+	}
+
+	statements, _ := d.unwind(fmt.Sprintf("UNWIND $rows AS nid MATCH (n:%s {nid:nid}) DETACH DELETE n;", d.label), rows)
+	if err := d.run(ctx, "DeleteNodes", statements); err != nil {
+		return nil, err
+	}
+
+	results := make(backend.NodeResults, len(*nodes))
+	for nid := range *nodes {
+		results[nid] = nil
+	}
+	return results, nil
+}
+
+// unwindNodes builds the UNWIND statements writing the nodes, each row holding the nid and the parameters of
+// the SET clause.  Nodes whose properties are invalid are left out and their error is recorded in results, every
+// other node starts out as backend.ErrNotFound until a row comes back for it.
+func (d *Driver) unwindNodes(nodes *backend.Nodes, results backend.NodeResults, statement func(set string) string) ([]*neoism.CypherQuery, []*[]neoResponse) {
+
+	groups := make(map[string][]interface{})
 	for nid, properties := range *nodes {
-		buffer := bytes.NewBufferString("")
-		for k, v := range properties {
-			if k == "nid" {
-				nid = v.(string)
+		set, params, err := setClause("n", "row.", properties)
+		if err != nil {
+			results[nid] = err
+			continue
+		}
+		params["nid"] = nid
+		groups[set] = append(groups[set], params)
+		results[nid] = backend.ErrNotFound
+	}
+	return d.unwindGroups(groups, statement)
+}
+
+// unwindEdges builds the UNWIND statements writing the edges, each row holding the from and to nids and the
+// parameters of the SET clause.  Errors are recorded in results the same way as unwindNodes does.  Since the rows
+// of a statement do not see each other's writes, only the edge with the smallest to nid is written when several
+// out of the same nid are given the same name, the others are reported as backend.ErrAlreadyExists.
+func (d *Driver) unwindEdges(edges *backend.Edges, results backend.EdgeResults, statement func(set string) string) ([]*neoism.CypherQuery, []*[]neoResponse) {
+
+	groups := make(map[string][]interface{})
+	for fid, tos := range *edges {
+		tids := make([]string, 0, len(tos))
+		for tid := range tos {
+			tids = append(tids, tid)
+		}
+		sort.Strings(tids)
+
+		names := make(map[string]bool)
+		for _, tid := range tids {
+			properties := tos[tid]
+			set, params, err := setClause("e", "row.", properties)
+			if err != nil {
+				results.Set(fid, tid, err)
 				continue
 			}
-			switch v.(type) {
-			case int, int32, int64, float32, float64:
-				buffer.WriteString(fmt.Sprintf("n.%s=%d", k, v))
-			case string:
-				buffer.WriteString(fmt.Sprintf("n.%s='%s'", k, v))
+			params["from"], params["to"] = fid, tid
+			if name, err := properties.GetString("name"); err == nil {
+				if names[name] {
+					results.Set(fid, tid, backend.ErrAlreadyExists)
+					continue
+				}
+				names[name] = true
+				params["name"] = name
 			}
-			buffer.WriteString(",")
+			groups[set] = append(groups[set], params)
+			results.Set(fid, tid, backend.ErrNotFound)
+		}
+	}
+	return d.unwindGroups(groups, statement)
+}
+
+// unwindGroups builds the UNWIND statements for rows grouped by the SET clause they need, so nodes or edges with
+// the same keys and types share statements.  statement builds the statement of a group from its clause.
+func (d *Driver) unwindGroups(groups map[string][]interface{}, statement func(set string) string) ([]*neoism.CypherQuery, []*[]neoResponse) {
+
+	// sort the groups so the statements come out in the same order every time
+	sets := make([]string, 0, len(groups))
+	for set := range groups {
+		sets = append(sets, set)
+	}
+	sort.Strings(sets)
+
+	var (
+		statements []*neoism.CypherQuery
+		responses  []*[]neoResponse
+	)
+	for _, set := range sets {
+		s, r := d.unwind(statement(set), groups[set])
+		statements = append(statements, s...)
+		responses = append(responses, r...)
+	}
+	return statements, responses
+}
+
+// unwind builds one statement per batchSize rows, each passing its rows to the query as $rows
+func (d *Driver) unwind(query string, rows []interface{}) ([]*neoism.CypherQuery, []*[]neoResponse) {
+
+	statements := make([]*neoism.CypherQuery, 0, len(rows)/d.batchSize+1)
+	responses := make([]*[]neoResponse, 0, len(rows)/d.batchSize+1)
+	for start := 0; start < len(rows); start += d.batchSize {
+		end := start + d.batchSize
+		if end > len(rows) {
+			end = len(rows)
 		}
-		buffer.Truncate(buffer.Len() - 1)
 
-		// Each statment will get a res struct to house the returned node from Neo
 		r := &[]neoResponse{}
 		q := &neoism.CypherQuery{
-			// we need the back ticks for the label because some may start with a number
-			// and cypher requires that we back tick those.
-			Statement: fmt.Sprintf(createQuery, d.sid, nid, buffer.String()),
-			Result:    r,
+			Statement:  query,
+			Parameters: neoism.Props{"rows": rows[start:end]},
+			Result:     r,
 		}
-
 		statements = append(statements, q)
 		responses = append(responses, r)
 		log.Debug(q)
 	}
+	return statements, responses
+}
 
-	tx, err := d.Connection.Begin(statements)
-	if err != nil {
-		log.Debugf("Begin Tx error: %s", err.Error())
-		return nil, err
+// GetInEdges will get the edges pointing to a node, keyed by the to nid and then the from nid.  When from nids
+// are given only those edges are fetched, otherwise every edge coming into the node is, and edges that are
+// asked for but not found are reported as backend.ErrNotFound.
+func (d *Driver) GetInEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+
+	results := make(backend.EdgeResults, len(*edges))
+	all := make([]interface{}, 0, len(*edges))
+	pairs := make([]interface{}, 0, len(*edges))
+	for tid, froms := range *edges {
+		if len(froms) == 0 {
+			all = append(all, tid)
+			continue
+		}
+		for fid := range froms {
+			pairs = append(pairs, neoism.Props{"from": fid, "to": tid})
+			results.Set(tid, fid, backend.ErrNotFound)
+		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		log.Debugf("Commit Tx error: %s", err.Error())
+	statements, responses := d.unwind(
+		fmt.Sprintf("UNWIND $rows AS nid MATCH (a:%s)-[e:%s]->(:%s {nid:nid}) RETURN a.nid AS from, nid AS to, e AS n;",
+			d.label, edgeType, d.label),
+		all,
+	)
+	s, r := d.unwind(d.edgeQuery("RETURN row.from AS from, row.to AS to, e AS n;"), pairs)
+	statements, responses = append(statements, s...), append(responses, r...)
+
+	if err := d.run(ctx, "GetInEdges", statements); err != nil {
 		return nil, err
 	}
 
-	// Translate the nodes into a valid backend node
-	bn := make(backend.Nodes, len(*nodes))
+	// Translate the relationships into a valid backend edge
 	for _, r := range responses {
-		resp := (*r)[0].Data
-		if resp == nil {
-			continue
+		for _, row := range *r {
+			*edges.GetEdgeByID(row.To, row.From) = *toProperties(row.Data)
+			results.Set(row.To, row.From, nil)
 		}
-		bn[resp["nid"].(string)] = resp
 	}
-
-	return &bn, nil
+	return results, nil
 }
 
-// DeleteNodes will delete the given nodes from the graph. All relationships
-// must be deleted before nodes can be deleted.
-func (d *Driver) DeleteNodes(nodes *backend.Nodes) error {
+// GetOutEdges will get the edges extending from a parent node, keyed by the from nid and then the to nid.  When
+// to nids are given only those edges are fetched, otherwise every edge going out of the parent is, and edges
+// that are asked for but not found are reported as backend.ErrNotFound.
+func (d *Driver) GetOutEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+
+	results := make(backend.EdgeResults, len(*edges))
+	all := make([]interface{}, 0, len(*edges))
+	pairs := make([]interface{}, 0, len(*edges))
+	for fid, tos := range *edges {
+		if len(tos) == 0 {
+			all = append(all, fid)
+			continue
+		}
+		for tid := range tos {
+			pairs = append(pairs, neoism.Props{"from": fid, "to": tid})
+			results.Set(fid, tid, backend.ErrNotFound)
+		}
+	}
 
-	statements := make([]*neoism.CypherQuery, 0, len(*nodes))
+	statements, responses := d.unwind(
+		fmt.Sprintf("UNWIND $rows AS nid MATCH (:%s {nid:nid})-[e:%s]->(b:%s) RETURN nid AS from, b.nid AS to, e AS n;",
+			d.label, edgeType, d.label),
+		all,
+	)
+	s, r := d.unwind(d.edgeQuery("RETURN row.from AS from, row.to AS to, e AS n;"), pairs)
+	statements, responses = append(statements, s...), append(responses, r...)
 
-	for nid := range *nodes {
+	if err := d.run(ctx, "GetOutEdges", statements); err != nil {
+		return nil, err
+	}
 
-		q := &neoism.CypherQuery{
-			// we need the back ticks for the label because some may start with a number
-			// and cypher requires that we back tick those.
-			Statement: fmt.Sprintf("MATCH (n:`%s` {nid:'%s'}) DELETE n;", d.sid, nid),
+	// Translate the relationships into a valid backend edge
+	for _, r := range responses {
+		for _, row := range *r {
+			*edges.GetEdgeByID(row.From, row.To) = *toProperties(row.Data)
+			results.Set(row.From, row.To, nil)
 		}
-
-		statements = append(statements, q)
-		log.Debug(q)
 	}
+	return results, nil
+}
 
-	tx, err := d.Connection.Begin(statements)
-	if err != nil {
-		log.Debugf("Begin Tx error: %s", err.Error())
-		return err
-	}
-	err = tx.Commit()
-	if err != nil {
-		log.Debugf("Commit Tx error: %s", err.Error())
-		return err
+// GetSingleEdge returns one edge that is between two nids
+func (d *Driver) GetSingleEdge(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+	return d.GetOutEdges(ctx, edges)
+}
+
+// CreateEdges creates a relationship for each edge with its properties.  Both nodes must exist, otherwise the
+// edge is reported as backend.ErrNotFound.  An edge that already exists between the same two nids, or whose name
+// is already taken by another relationship out of the same node, is left unchanged and reported as
+// backend.ErrAlreadyExists.
+func (d *Driver) CreateEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+
+	createQuery := `UNWIND $rows AS row
+	MATCH (a:%[1]s {nid:row.from}), (b:%[1]s {nid:row.to})
+	OPTIONAL MATCH (a)-[x:%[2]s]->(c) WHERE c = b OR x.name = row.name
+	WITH row, a, b, count(x) = 0 AS created
+	FOREACH (_ IN CASE WHEN created THEN [1] ELSE [] END |
+		CREATE (a)-[e:%[2]s]->(b)%[3]s)
+	WITH row, a, b, created
+	OPTIONAL MATCH (a)-[e:%[2]s]->(b)
+	RETURN row.from AS from, row.to AS to, e AS n, created;`
+
+	results := make(backend.EdgeResults, len(*edges))
+	statements, responses := d.unwindEdges(edges, results, func(set string) string {
+		if set != "" {
+			set = " SET " + set
+		}
+		return fmt.Sprintf(createQuery, d.label, edgeType, set)
+	})
+	if err := d.run(ctx, "CreateEdges", statements); err != nil {
+		return nil, err
 	}
 
-	return nil
+	// Translate the relationships into a valid backend edge.  An edge refused for its name has no relationship
+	// between its nodes, so its properties are left as they were given.
+	for _, r := range responses {
+		for _, row := range *r {
+			if !row.Created {
+				if row.Data != nil {
+					(*edges)[row.From][row.To] = toProperties(row.Data)
+				}
+				results.Set(row.From, row.To, backend.ErrAlreadyExists)
+				continue
+			}
+			(*edges)[row.From][row.To] = toProperties(row.Data)
+			results.Set(row.From, row.To, nil)
+		}
+	}
+	return results, nil
 }
 
-// GetInEdges returns all edges that are pointing to a nid
-func (d *Driver) GetInEdges(edges *backend.Edges) (*backend.Edges, error) {
+// AlterEdges changes properties on edges with the given properties.  If no
+// edge was found then nothing will happen and it is reported as
+// backend.ErrNotFound.  An edge renamed to a name already taken by another
+// relationship out of the same node is left unchanged and reported as
+// backend.ErrAlreadyExists, the same as CreateEdges does.
+func (d *Driver) AlterEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+
+	// created reports whether the SET went through
+	alterQuery := `UNWIND $rows AS row
+	MATCH (a:%[1]s {nid:row.from})-[e:%[2]s]->(:%[1]s {nid:row.to})
+	OPTIONAL MATCH (a)-[x:%[2]s]->() WHERE x <> e AND x.name = row.name
+	WITH row, e, count(x) = 0 AS created
+	FOREACH (_ IN CASE WHEN created THEN [1] ELSE [] END | SET %[3]s)
+	RETURN row.from AS from, row.to AS to, e AS n, created;`
+
+	results := make(backend.EdgeResults, len(*edges))
+	statements, responses := d.unwindEdges(edges, results, func(set string) string {
+		if set == "" {
+			// nothing to set, only check the edges exist
+			return d.edgeQuery("RETURN row.from AS from, row.to AS to, e AS n, true AS created;")
+		}
+		return fmt.Sprintf(alterQuery, d.label, edgeType, set)
+	})
+	if err := d.run(ctx, "AlterEdges", statements); err != nil {
+		return nil, err
+	}
 
-	statements := make([]*neoism.CypherQuery, 0, len(*edges))
-	responses := make([]*[]neoResponse, 0, len(*edges))
+	// Translate the relationships into a valid backend edge
+	for _, r := range responses {
+		for _, row := range *r {
+			(*edges)[row.From][row.To] = toProperties(row.Data)
+			if !row.Created {
+				results.Set(row.From, row.To, backend.ErrAlreadyExists)
+				continue
+			}
+			results.Set(row.From, row.To, nil)
+		}
+	}
+	return results, nil
+}
 
-	for nid := range *edges {
+// DeleteEdges removes edges from the graph.  Deleting an edge that does not exist is not an error.
+func (d *Driver) DeleteEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
 
-		r := &[]neoResponse{}
-		q := &neoism.CypherQuery{
-			Statement: fmt.Sprintf("MATCH ()-[n:ROOT*]->(m:`%s` {nid:'%s'}) RETURN n;", d.sid, nid),
-			Result:    r,
+	rows := make([]interface{}, 0, len(*edges))
+	for fid, tos := range *edges {
+		for tid := range tos {
+			rows = append(rows, neoism.Props{"from": fid, "to": tid})
 		}
-
-		statements = append(statements, q)
-		responses = append(responses, r)
-		log.Debug(q)
 	}
 
-	tx, err := d.Connection.Begin(statements)
-	if err != nil {
-		log.Debugf("Begin Tx error: %s", err.Error())
-		return nil, err
-	}
-	err = tx.Commit()
-	if err != nil {
-		log.Debugf("Commit Tx error: %s", err.Error())
+	statements, _ := d.unwind(d.edgeQuery("DELETE e;"), rows)
+	if err := d.run(ctx, "DeleteEdges", statements); err != nil {
 		return nil, err
 	}
 
-	// Translate the nodes into a valid backend node
-	be := make(backend.Edges, len(*edges))
-	for i, r := range responses {
-		resp := (*r)[0].Data
-		if resp == nil {
-			continue
+	results := make(backend.EdgeResults, len(*edges))
+	for fid, tos := range *edges {
+		for tid := range tos {
+			results.Set(fid, tid, nil)
 		}
-		temp := strconv.Itoa(i)
-		be[temp] = resp
 	}
-	return &be, nil
+	return results, nil
 }
 
-// GetOutEdges returns all edges that are originating from a nid
-func (d *Driver) GetOutEdges(edges *backend.Edges) (*backend.Edges, error) {
-	return nil, nil
+// edgeQuery returns an UNWIND query matching the edge e between the from and to nids of each row followed by
+// the rest of the query
+func (d *Driver) edgeQuery(rest string) string {
+	return fmt.Sprintf("UNWIND $rows AS row MATCH (:%s {nid:row.from})-[e:%s]->(:%s {nid:row.to}) %s",
+		d.label, edgeType, d.label, rest)
 }
 
-// GetSingleEdge returns one edge that is between two nids
-func (d *Driver) GetSingleEdge(edges *backend.Edges) (*backend.Edges, error) {
-	return nil, nil
+type pathResponse struct {
+	Data map[string]interface{} `json:"e"`
+	Nid  string                 `json:"nid"`
 }
 
-// CreateEdges creates edges with properties
-func (d *Driver) CreateEdges(edges *backend.Edges) (*backend.Edges, error) {
-	return nil, nil
-}
+// GetPath resolves a slash separated path starting at the root nid, following the relationship with the
+// matching name at each level.
+func (d *Driver) GetPath(ctx context.Context, root string, path string) (*backend.Path, error) {
 
-// AlterEdges changes properties on edges with the given properties
-func (d *Driver) AlterEdges(edges *backend.Edges) (*backend.Edges, error) {
-	return nil, nil
+	p, err := backend.ResolvePath(ctx, root, path, d.lookupEdgeByName)
+	if err != nil {
+		return nil, err
+	}
+	return p, p.FillNodes(ctx, d.GetNodes)
 }
 
-// DeleteEdges removes edges from the graph
-func (d *Driver) DeleteEdges(edges *backend.Edges) error {
-	return nil
-}
+// lookupEdgeByName finds the relationship going out of the parent that has the given name and returns the nid
+// it points to along with the relationship properties, or an empty nid if there is no such relationship.
+func (d *Driver) lookupEdgeByName(ctx context.Context, parent, name string) (string, *backend.Properties, error) {
+
+	r := &[]pathResponse{}
+	q := &neoism.CypherQuery{
+		Statement: fmt.Sprintf(
+			"MATCH (:%s {nid:$parent})-[e:%s {name:$name}]->(m:%s) RETURN e, m.nid AS nid ORDER BY nid LIMIT 1;",
+			d.label, edgeType, d.label,
+		),
+		Parameters: neoism.Props{"parent": parent, "name": name},
+		Result:     r,
+	}
+	log.Debug(q)
 
-// GetPath returns a path with it's edeges given a series of nids
-func (d *Driver) GetPath(nodes *backend.Nodes) (*backend.Path, error) {
-	return nil, nil
+	if err := d.run(ctx, "GetPath", []*neoism.CypherQuery{q}); err != nil {
+		return "", nil, err
+	}
+	if len(*r) == 0 {
+		return "", nil, nil
+	}
+	return (*r)[0].Nid, toProperties((*r)[0].Data), nil
 }
 
 // GetConnection gets a new connection to the db
@@ -0,0 +1,126 @@
+package neo
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/sir-wiggles/bcfs/backend"
+	"github.com/sir-wiggles/bcfs/backend/graphtest"
+)
+
+// Test_Graph runs against the neo server given by NEO_HOST, NEO_USER and NEO_PASSWORD and is skipped when
+// NEO_HOST is not set
+func Test_Graph(t *testing.T) {
+	host := os.Getenv("NEO_HOST")
+	if host == "" {
+		t.Skip("NEO_HOST is not set")
+	}
+
+	graphtest.Run(t, newDriver, backend.Config{
+		"name":     PackageName,
+		"user":     os.Getenv("NEO_USER"),
+		"password": os.Getenv("NEO_PASSWORD"),
+		"host":     host,
+		"port":     7474,
+	})
+}
+
+// Test_GraphBolt runs the same tests as Test_Graph over the bolt transport, with the port given by NEO_BOLT_PORT
+func Test_GraphBolt(t *testing.T) {
+	host := os.Getenv("NEO_HOST")
+	if host == "" {
+		t.Skip("NEO_HOST is not set")
+	}
+	port := DefaultBoltPort
+	if p := os.Getenv("NEO_BOLT_PORT"); p != "" {
+		var err error
+		if port, err = strconv.Atoi(p); err != nil {
+			t.Fatalf("invalid NEO_BOLT_PORT %s", p)
+		}
+	}
+
+	graphtest.Run(t, newDriver, backend.Config{
+		"name":      PackageName,
+		"transport": TransportBolt,
+		"user":      os.Getenv("NEO_USER"),
+		"password":  os.Getenv("NEO_PASSWORD"),
+		"host":      host,
+		"port":      port,
+	})
+}
+
+func Test_unwindNodes(t *testing.T) {
+	d := &Driver{label: "`test`", batchSize: 2}
+
+	nodes := &backend.Nodes{}
+	for _, nid := range []string{"1", "2", "3"} {
+		properties := &backend.Properties{}
+		properties.SetString("name", nid)
+		(*nodes)[nid] = properties
+	}
+	other := &backend.Properties{}
+	other.SetInt("size", 4)
+	(*nodes)["4"] = other
+	bad := &backend.Properties{}
+	bad.SetString("", "x")
+	(*nodes)["5"] = bad
+
+	results := make(backend.NodeResults)
+	statements, responses := d.unwindNodes(nodes, results, func(set string) string { return set })
+
+	// the three nodes with a name need two batches and the one with a size a third
+	if len(statements) != 3 || len(responses) != 3 {
+		t.Fatalf("expected 3 statements got %d", len(statements))
+	}
+	rows := 0
+	for _, statement := range statements {
+		rows += len(statement.Parameters["rows"].([]interface{}))
+	}
+	if rows != 4 {
+		t.Errorf("expected 4 rows got %d", rows)
+	}
+	if results["5"] == nil || errors.Is(results["5"], backend.ErrNotFound) {
+		t.Errorf("expected the invalid node to fail got %v", results["5"])
+	}
+	for _, nid := range []string{"1", "2", "3", "4"} {
+		if !errors.Is(results[nid], backend.ErrNotFound) {
+			t.Errorf("expected %s to be not found until a row comes back got %v", nid, results[nid])
+		}
+	}
+}
+
+func Test_unwindEdges(t *testing.T) {
+	d := &Driver{label: "`test`", batchSize: 10}
+
+	named := func(name string) *backend.Properties {
+		properties := &backend.Properties{}
+		properties.SetString("name", name)
+		return properties
+	}
+	edges := &backend.Edges{
+		"root": {"1": named("a"), "2": named("a"), "3": named("b")},
+		"4":    {"1": named("a")},
+	}
+
+	results := make(backend.EdgeResults)
+	statements, _ := d.unwindEdges(edges, results, func(set string) string { return set })
+
+	// the edge with the smallest to nid keeps a name given twice under the same parent
+	rows := 0
+	for _, statement := range statements {
+		rows += len(statement.Parameters["rows"].([]interface{}))
+	}
+	if rows != 3 {
+		t.Errorf("expected 3 rows got %d", rows)
+	}
+	if !errors.Is(results["root"]["2"], backend.ErrAlreadyExists) {
+		t.Errorf("expected root -> 2 to lose the name got %v", results["root"]["2"])
+	}
+	for _, pair := range [][2]string{{"root", "1"}, {"root", "3"}, {"4", "1"}} {
+		if !errors.Is(results[pair[0]][pair[1]], backend.ErrNotFound) {
+			t.Errorf("expected %s -> %s to be not found until a row comes back got %v", pair[0], pair[1], results[pair[0]][pair[1]])
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package neo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// Neo only stores strings, numbers, booleans, temporal values and flat lists of those, and the REST API hands
+// everything back as JSON, so a property whose type can not be told from its JSON value gets a second property
+// named with typePrefix followed by its name holding a hint of its backend type.  Maps and lists neo can not
+// hold are stored as the JSON encoding of the backend property with a "json" hint.
+const (
+	typePrefix = "_type_"
+
+	hintNumber = "number"
+	hintBinary = "binary"
+	hintFloat  = "float"
+	hintTime   = "time"
+	hintJSON   = "json"
+	// list hints are followed by the hint of their elements, e.g. "list:float"
+	hintList = "list:"
+)
+
+// the largest integer that makes it through a JSON float64 unchanged, numbers beyond it or with a fractional
+// part are stored as strings to keep all of their digits
+const maxExactInteger = 1 << 53
+
+// neo hands datetimes back without the seconds when they are zero
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00"}
+
+// toProperties translates the data of a node or relationship returned from neo into backend properties
+func toProperties(data map[string]interface{}) *backend.Properties {
+	properties := make(backend.Properties, len(data))
+	for k, v := range data {
+		if strings.HasPrefix(k, typePrefix) {
+			continue
+		}
+		hint, _ := data[typePrefix+k].(string)
+		property, err := toProperty(v, hint)
+		if err != nil {
+			log.Debugf("Property %s: %s", k, err.Error())
+			continue
+		}
+		if property != nil {
+			properties[k] = property
+		}
+	}
+	return &properties
+}
+
+// toProperty translates a single value returned from neo into a backend property given its type hint
+func toProperty(v interface{}, hint string) (*backend.Property, error) {
+
+	switch {
+	case hint == hintNumber:
+		if s, ok := v.(string); ok {
+			n, err := backend.ParseNumber(s)
+			if err != nil {
+				return nil, err
+			}
+			return &backend.Property{Type: backend.NumberProperty, Value: n}, nil
+		}
+	case hint == hintBinary:
+		if s, ok := v.(string); ok {
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, err
+			}
+			return &backend.Property{Type: backend.BinaryProperty, Value: b}, nil
+		}
+	case hint == hintFloat:
+		if f, ok := v.(float64); ok {
+			return &backend.Property{Type: backend.FloatProperty, Value: f}, nil
+		}
+	case hint == hintTime:
+		// bolt hands back datetimes as they are, HTTP as strings
+		if t, ok := v.(time.Time); ok {
+			return &backend.Property{Type: backend.TimeProperty, Value: t}, nil
+		}
+		if s, ok := v.(string); ok {
+			for _, layout := range timeLayouts {
+				if t, err := time.Parse(layout, s); err == nil {
+					return &backend.Property{Type: backend.TimeProperty, Value: t}, nil
+				}
+			}
+			return nil, fmt.Errorf("invalid time %s", s)
+		}
+	case hint == hintJSON:
+		if s, ok := v.(string); ok {
+			property := &backend.Property{}
+			if err := json.Unmarshal([]byte(s), property); err != nil {
+				return nil, err
+			}
+			return property, nil
+		}
+	case strings.HasPrefix(hint, hintList):
+		if l, ok := v.([]interface{}); ok {
+			list := make([]*backend.Property, 0, len(l))
+			for _, element := range l {
+				property, err := toProperty(element, strings.TrimPrefix(hint, hintList))
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, property)
+			}
+			return &backend.Property{Type: backend.ListProperty, Value: list}, nil
+		}
+	}
+
+	switch vv := v.(type) {
+	case string:
+		return &backend.Property{Type: backend.StringProperty, Value: vv}, nil
+	case float64:
+		n, err := backend.NumberFromFloat64(vv)
+		if err != nil {
+			return nil, err
+		}
+		return &backend.Property{Type: backend.NumberProperty, Value: n}, nil
+	case int64:
+		return &backend.Property{Type: backend.NumberProperty, Value: backend.NumberFromInt64(vv)}, nil
+	case bool:
+		return &backend.Property{Type: backend.BoolProperty, Value: vv}, nil
+	case time.Time:
+		return &backend.Property{Type: backend.TimeProperty, Value: vv}, nil
+	case []byte:
+		return &backend.Property{Type: backend.BinaryProperty, Value: vv}, nil
+	case []interface{}:
+		list := make([]*backend.Property, 0, len(vv))
+		for _, element := range vv {
+			property, err := toProperty(element, "")
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, property)
+		}
+		return &backend.Property{Type: backend.ListProperty, Value: list}, nil
+	case nil:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported value %T", v)
+}
+
+// setClause builds the assignments of a SET clause for the given variable along with the parameters they refer
+// to, leaving out the nid since it is the index and should never be altered.  Parameters are referred to by
+// their name prefixed with ref, which is "$" for statement parameters or e.g. "row." for the fields of an
+// UNWIND row.  Every value is passed as a parameter and every key is validated and escaped so nothing the caller
+// gives ends up as cypher.  The type hint of every property is set along with it, or removed by setting it to
+// null, so a stale hint never outlives a type change.
+func setClause(variable, ref string, properties *backend.Properties) (string, map[string]interface{}, error) {
+	keys := make([]string, 0, len(*properties))
+	for k := range *properties {
+		if k != "nid" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	assignments := make([]string, 0, 2*len(keys))
+	params := make(map[string]interface{}, 2*len(keys))
+	for i, k := range keys {
+		field, err := propertyKey(k)
+		if err != nil {
+			return "", nil, err
+		}
+		value, hint, expression, err := parameter((*properties)[k])
+		if err != nil {
+			return "", nil, fmt.Errorf("Invalid %s property: %w", k, err)
+		}
+
+		p, t := fmt.Sprintf("p%d", i), fmt.Sprintf("t%d", i)
+		params[p] = value
+		assignments = append(assignments, fmt.Sprintf("%s.%s=%s", variable, field, fmt.Sprintf(expression, ref+p)))
+		if hint == "" {
+			assignments = append(assignments, fmt.Sprintf("%s.%s=null", variable, escape(typePrefix+k)))
+			continue
+		}
+		params[t] = hint
+		assignments = append(assignments, fmt.Sprintf("%s.%s=%s%s", variable, escape(typePrefix+k), ref, t))
+	}
+	return strings.Join(assignments, ","), params, nil
+}
+
+// parameter returns the value of a property as a cypher parameter along with its type hint, empty when none is
+// needed, and the expression turning the parameter into the value to store with %s standing for the parameter
+func parameter(property *backend.Property) (interface{}, string, string, error) {
+	if property == nil {
+		// setting a property to null removes it
+		return nil, "", "%s", nil
+	}
+	switch property.Type {
+	case backend.StringProperty:
+		if s, ok := property.Value.(string); ok {
+			return s, "", "%s", nil
+		}
+	case backend.NumberProperty:
+		if n, ok := property.Value.(backend.Number); ok {
+			if i, err := n.Int64(); err == nil && -maxExactInteger <= i && i <= maxExactInteger {
+				return i, "", "%s", nil
+			}
+			return n.String(), hintNumber, "%s", nil
+		}
+	case backend.BinaryProperty:
+		if b, ok := property.Value.([]byte); ok {
+			return base64.StdEncoding.EncodeToString(b), hintBinary, "%s", nil
+		}
+	case backend.BoolProperty:
+		if b, ok := property.Value.(bool); ok {
+			return b, "", "%s", nil
+		}
+	case backend.FloatProperty:
+		if f, ok := property.Value.(float64); ok && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f, hintFloat, "toFloat(%s)", nil
+		}
+	case backend.TimeProperty:
+		if t, ok := property.Value.(time.Time); ok {
+			return t.Format(time.RFC3339Nano), hintTime, "datetime(%s)", nil
+		}
+	case backend.ListProperty:
+		if l, ok := property.Value.([]*backend.Property); ok {
+			if value, hint, expression, ok := nativeList(l); ok {
+				return value, hint, expression, nil
+			}
+			return jsonParameter(property)
+		}
+	case backend.MapProperty:
+		return jsonParameter(property)
+	}
+	return nil, "", "", fmt.Errorf("unsupported %s value %T", property.Type, property.Value)
+}
+
+// nativeList returns the parameter of a list neo can store as is, which is one whose elements are all of the
+// same type, stored the same way, and that type is not itself a list or map
+func nativeList(l []*backend.Property) (interface{}, string, string, bool) {
+	elements := make([]interface{}, len(l))
+	elementHint, elementExpression := "", "%s"
+	for i, element := range l {
+		if element.Type != l[0].Type || element.Type == backend.ListProperty || element.Type == backend.MapProperty {
+			return nil, "", "", false
+		}
+		value, hint, expression, err := parameter(element)
+		if err != nil || (i > 0 && hint != elementHint) {
+			return nil, "", "", false
+		}
+		elements[i], elementHint, elementExpression = value, hint, expression
+	}
+	expression := "%s"
+	if elementExpression != "%s" {
+		expression = "[x IN %s | " + fmt.Sprintf(elementExpression, "x") + "]"
+	}
+	return elements, hintList + elementHint, expression, true
+}
+
+// jsonParameter returns the property encoded as JSON
+func jsonParameter(property *backend.Property) (interface{}, string, string, error) {
+	b, err := json.Marshal(property)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return string(b), hintJSON, "%s", nil
+}
+
+// propertyKey validates a property key given by the caller and returns it escaped for cypher.  Keys starting
+// with the type hint prefix are reserved for the driver.
+func propertyKey(k string) (string, error) {
+	if strings.HasPrefix(k, typePrefix) {
+		return "", fmt.Errorf("Invalid property key %q: the %s prefix is reserved", k, typePrefix)
+	}
+	return identifier(k)
+}
+
+// identifier validates a label or property key and returns it escaped for cypher.  Anything but an empty name
+// or one holding a NUL can be used once escaped.
+func identifier(name string) (string, error) {
+	if name == "" || strings.ContainsRune(name, 0) {
+		return "", fmt.Errorf("Invalid name %q", name)
+	}
+	return escape(name), nil
+}
+
+// escape quotes a name with backticks so it is taken as is by cypher
+func escape(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
@@ -0,0 +1,82 @@
+package neo
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+func Test_setClause(t *testing.T) {
+	properties := &backend.Properties{}
+	properties.SetString("nid", "ignored")
+	properties.SetString("name", "it's a `file`")
+	properties.SetString("a`}) DETACH DELETE n //", "x")
+	properties.SetTime("modified", time.Date(2017, 6, 1, 12, 30, 0, 0, time.UTC))
+
+	// + test
+	set, params, err := setClause("n", "$", properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "n.`a``}) DETACH DELETE n //`=$p0,n.`_type_a``}) DETACH DELETE n //`=null," +
+		"n.`modified`=datetime($p1),n.`_type_modified`=$t1," +
+		"n.`name`=$p2,n.`_type_name`=null"
+	if set != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, set)
+	}
+	if params["p2"] != "it's a `file`" || params["t1"] != hintTime || params["p1"] != "2017-06-01T12:30:00Z" {
+		t.Errorf("unexpected parameters %v", params)
+	}
+
+	// - test
+	for _, key := range []string{"", "a\x00b", typePrefix + "name"} {
+		bad := &backend.Properties{}
+		bad.SetString(key, "x")
+		if _, _, err := setClause("n", "$", bad); err == nil {
+			t.Errorf("%q: expected an error", key)
+		}
+	}
+}
+
+func Test_toProperties(t *testing.T) {
+	data := map[string]interface{}{
+		"size":         float64(12),
+		"ratio":        float64(3),
+		"_type_ratio":  hintFloat,
+		"big":          "123456789012345678901234567890",
+		"_type_big":    hintNumber,
+		"at":           "2017-06-01T12:30Z",
+		"_type_at":     hintTime,
+		"floats":       []interface{}{float64(1), 2.5},
+		"_type_floats": hintList + hintFloat,
+		"meta":         `{"type":"map","value":{"hidden":{"type":"bool","value":true}}}`,
+		"_type_meta":   hintJSON,
+	}
+
+	properties := toProperties(data)
+	if n, _ := properties.GetNumber("size"); n != backend.NumberFromInt64(12) {
+		t.Errorf("expected size 12 got %s", n)
+	}
+	if f, err := properties.GetFloat("ratio"); err != nil || f != 3 {
+		t.Errorf("expected ratio 3 got %v %v", f, err)
+	}
+	if n, _ := properties.GetNumber("big"); n.String() != "123456789012345678901234567890" {
+		t.Errorf("expected big to keep every digit got %s", n)
+	}
+	if at, err := properties.GetTime("at"); err != nil || !at.Equal(time.Date(2017, 6, 1, 12, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected time %v %v", at, err)
+	}
+	if l, err := properties.GetList("floats"); err != nil || len(l) != 2 || l[0].Type != backend.FloatProperty {
+		t.Errorf("expected a list of floats got %v %v", l, err)
+	}
+	if m, err := properties.GetMap("meta"); err != nil || (*m)["hidden"].Value != true {
+		t.Errorf("expected meta.hidden to be true got %v %v", m, err)
+	}
+	for key := range *properties {
+		if strings.HasPrefix(key, typePrefix) {
+			t.Errorf("type hint %s should not be returned as a property", key)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package neo
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmcvetta/neoism"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// schemaObject is an index or constraint the driver relies on
+type schemaObject struct {
+	name string
+	// create creates the object unless one with the same name exists
+	create string
+}
+
+type nameResponse struct {
+	Name string `json:"name"`
+}
+
+// schema returns the indexes and constraints the driver needs for its sid.  The uniqueness constraint on nid
+// comes with an index so nodes are looked up without scanning the label, and the index on the edge name is
+// shared by every sid since they all use the same relationship type.
+func (d *Driver) schema() []schemaObject {
+	nid := "bcfs_nid_" + d.sid
+	name := "bcfs_edge_name"
+	return []schemaObject{
+		{
+			name:   nid,
+			create: fmt.Sprintf("CREATE CONSTRAINT %s IF NOT EXISTS FOR (n:%s) REQUIRE n.nid IS UNIQUE", escape(nid), d.label),
+		},
+		{
+			name:   name,
+			create: fmt.Sprintf("CREATE INDEX %s IF NOT EXISTS FOR ()-[e:%s]-() ON (e.name)", escape(name), edgeType),
+		},
+	}
+}
+
+// Provision creates the indexes and constraints of the sid that do not exist yet and reports them by name
+func (d *Driver) Provision(ctx context.Context) (*backend.ProvisionReport, error) {
+
+	constraints := &[]nameResponse{}
+	indexes := &[]nameResponse{}
+	err := d.exec(ctx, "Provision", []*neoism.CypherQuery{
+		{Statement: "SHOW CONSTRAINTS YIELD name RETURN name", Result: constraints},
+		{Statement: "SHOW INDEXES YIELD name RETURN name", Result: indexes},
+	})
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(*constraints)+len(*indexes))
+	for _, r := range append(*constraints, *indexes...) {
+		existing[r.Name] = true
+	}
+
+	report := &backend.ProvisionReport{}
+	for _, object := range d.schema() {
+		if existing[object.name] {
+			report.Existing = append(report.Existing, object.name)
+			continue
+		}
+		// schema changes can not share a transaction with anything else
+		q := &neoism.CypherQuery{Statement: object.create}
+		log.Debug(q)
+		if err := d.exec(ctx, "Provision", []*neoism.CypherQuery{q}); err != nil {
+			return report, err
+		}
+		report.Created = append(report.Created, object.name)
+	}
+
+	d.schemaMu.Lock()
+	d.schemaReady = true
+	d.schemaMu.Unlock()
+	return report, nil
+}
+
+// ensureSchema provisions the sid the first time the driver is used unless the config turned it off.  A failed
+// attempt is retried on the next use.
+func (d *Driver) ensureSchema(ctx context.Context) error {
+	if !d.autoSchema {
+		return nil
+	}
+	d.schemaMu.Lock()
+	ready := d.schemaReady
+	d.schemaMu.Unlock()
+	if ready {
+		return nil
+	}
+
+	report, err := d.Provision(ctx)
+	if err != nil {
+		return err
+	}
+	if len(report.Created) > 0 {
+		log.Infof("Created the neo schema of %s: %v", d.sid, report.Created)
+	}
+	return nil
+}
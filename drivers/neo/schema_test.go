@@ -0,0 +1,27 @@
+package neo
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_schema(t *testing.T) {
+	d := &Driver{sid: "a`b", label: escape("a`b")}
+
+	schema := d.schema()
+	if len(schema) != 2 {
+		t.Fatalf("expected 2 schema objects got %d", len(schema))
+	}
+	if schema[0].name != "bcfs_nid_a`b" {
+		t.Errorf("unexpected constraint name %s", schema[0].name)
+	}
+	expected := "CREATE CONSTRAINT `bcfs_nid_a``b` IF NOT EXISTS FOR (n:`a``b`) REQUIRE n.nid IS UNIQUE"
+	if schema[0].create != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, schema[0].create)
+	}
+	for _, object := range schema {
+		if !strings.Contains(object.create, "IF NOT EXISTS") {
+			t.Errorf("%s: creating the schema should be idempotent", object.name)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package neo
+
+import (
+	"context"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmcvetta/neoism"
+)
+
+// Transports the driver can talk to neo over, chosen with the transport key of the config
+const (
+	// TransportREST uses the legacy HTTP transaction endpoint through neoism
+	TransportREST = "rest"
+	// TransportBolt uses the Bolt protocol with a pool of connections
+	TransportBolt = "bolt"
+)
+
+// transport starts transactions against neo
+type transport interface {
+	begin(ctx context.Context) (transaction, error)
+	close(ctx context.Context) error
+}
+
+// transaction runs statements against neo.  Every statement given to run is executed in the transaction, and
+// the rows each statement returns are decoded into its Result, which is a pointer to a slice of structs whose
+// json tags name the returned columns.  After a failed run the transaction can only be rolled back.
+type transaction interface {
+	run(ctx context.Context, statements []*neoism.CypherQuery) error
+	commit(ctx context.Context) error
+	rollback(ctx context.Context) error
+}
+
+// restTransport runs statements over neo's HTTP transaction endpoint
+type restTransport struct {
+	db *neoism.Database
+}
+
+// begin returns a transaction that is only opened on the server along with its first statements, saving a
+// round trip
+func (t *restTransport) begin(ctx context.Context) (transaction, error) {
+	return &restTransaction{db: t.db}, nil
+}
+
+// close does nothing since every HTTP call stands on its own
+func (t *restTransport) close(ctx context.Context) error {
+	return nil
+}
+
+// restTransaction is a transaction over HTTP.  neoism does not take a context for its HTTP calls so the context
+// is checked before every call instead.
+type restTransaction struct {
+	db *neoism.Database
+	tx *neoism.Tx
+}
+
+func (t *restTransaction) run(ctx context.Context, statements []*neoism.CypherQuery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.tx == nil {
+		tx, err := t.db.Begin(statements)
+		if err != nil {
+			log.Debugf("Begin Tx error: %s", err.Error())
+			return err
+		}
+		t.tx = tx
+		return nil
+	}
+	return t.tx.Query(statements)
+}
+
+// commit commits the transaction, or rolls it back if the caller has given up in the meantime
+func (t *restTransaction) commit(ctx context.Context) error {
+	if t.tx == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		if rerr := t.tx.Rollback(); rerr != nil {
+			log.Debugf("Rollback Tx error: %s", rerr.Error())
+		}
+		return err
+	}
+	if err := t.tx.Commit(); err != nil {
+		log.Debugf("Commit Tx error: %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (t *restTransaction) rollback(ctx context.Context) error {
+	if t.tx == nil {
+		return nil
+	}
+	return t.tx.Rollback()
+}
+
+// joinedTransport hands out the transaction in progress so that every call made by the driver of a
+// transaction becomes part of it.  Committing and rolling back is left to whoever began the transaction.
+type joinedTransport struct {
+	tx transaction
+}
+
+func (t *joinedTransport) begin(ctx context.Context) (transaction, error) {
+	return joined{t.tx}, nil
+}
+
+func (t *joinedTransport) close(ctx context.Context) error {
+	return nil
+}
+
+type joined struct {
+	transaction
+}
+
+func (joined) commit(ctx context.Context) error {
+	return nil
+}
+
+func (joined) rollback(ctx context.Context) error {
+	return nil
+}
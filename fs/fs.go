@@ -0,0 +1,455 @@
+// Package fs is the filesystem layer of bcfs.  It keeps files and folders as nodes in a backend.Graph, linked
+// from their parent folder by an edge carrying their name, and only talks to the graph through the
+// backend.Graph interface so it works with any registered driver.
+package fs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// The kinds of objects in the filesystem
+const (
+	File   = "file"
+	Folder = "folder"
+)
+
+// Keys of the properties the filesystem keeps on nodes and edges
+const (
+	// TypeKey is the node property holding whether the node is a file or a folder
+	TypeKey = "type"
+	// NameKey is the edge property holding the name of the object the edge points to
+	NameKey = "name"
+)
+
+// Errors returned by the filesystem on top of the backend ones
+var (
+	ErrNotFolder = fmt.Errorf("not a folder: %w", backend.ErrConflict)
+	ErrNotEmpty  = fmt.Errorf("folder is not empty: %w", backend.ErrConflict)
+	ErrRoot      = fmt.Errorf("the root folder can not be moved or deleted: %w", backend.ErrConflict)
+	ErrLoop      = fmt.Errorf("a folder can not be moved inside itself: %w", backend.ErrConflict)
+)
+
+// FS is a filesystem rooted at a folder node of the graph
+type FS struct {
+	graph backend.Graph
+	root  string
+}
+
+// New returns a filesystem on the graph rooted at the folder with the root nid
+func New(graph backend.Graph, root string) *FS {
+	return &FS{graph: graph, root: root}
+}
+
+// Object is a file or a folder along with where it lives in the filesystem
+type Object struct {
+	ID         string
+	Name       string
+	Path       string
+	Type       string
+	Properties *backend.Properties
+	// Children maps the names of the entries of a folder to their nids.  It is nil for files.
+	Children map[string]string
+}
+
+// IsFolder reports whether the object is a folder
+func (o *Object) IsFolder() bool {
+	return o.Type == Folder
+}
+
+// Init creates the root folder if it does not exist yet
+func (f *FS) Init(ctx context.Context) error {
+	results, err := f.graph.GetNodes(ctx, &backend.Nodes{f.root: &backend.Properties{}})
+	if err != nil {
+		return err
+	}
+	if !errors.Is(results[f.root], backend.ErrNotFound) {
+		return results[f.root]
+	}
+
+	root := &backend.Properties{}
+	root.SetString(TypeKey, Folder)
+	results, err = f.graph.CreateNodes(ctx, &backend.Nodes{f.root: root})
+	if err != nil {
+		return err
+	}
+	// another instance may have created it in the meantime
+	if err := results.Err(); !errors.Is(err, backend.ErrAlreadyExists) {
+		return err
+	}
+	return nil
+}
+
+// CreateFile creates a file at path with the given properties.  The parent folder must exist.
+func (f *FS) CreateFile(ctx context.Context, path string, properties *backend.Properties) (*Object, error) {
+	return f.create(ctx, path, File, properties)
+}
+
+// CreateFolder creates an empty folder at path with the given properties.  The parent folder must exist.
+func (f *FS) CreateFolder(ctx context.Context, path string, properties *backend.Properties) (*Object, error) {
+	return f.create(ctx, path, Folder, properties)
+}
+
+func (f *FS) create(ctx context.Context, path, kind string, properties *backend.Properties) (*Object, error) {
+
+	dir, name, err := split(path)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := f.folder(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := free(parent, dir, name); err != nil {
+		return nil, err
+	}
+
+	nid, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	node := &backend.Properties{}
+	if properties != nil {
+		node = properties.Clone()
+	}
+	node.SetString(TypeKey, kind)
+
+	// the node and its edge are written in a single transaction when the driver supports them
+	create := func(g backend.Graph) error {
+		return link(ctx, g, parent.ID, nid, name, node)
+	}
+	if t, ok := f.graph.(backend.Transactor); ok {
+		err = t.WithTx(ctx, create)
+	} else {
+		err = create(f.graph)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Get(ctx, join(dir, name))
+}
+
+// link creates the node nid and links it from parent under name.  Without a transaction a failure to create the
+// edge deletes the node again so nothing is left behind that can not be reached.
+func link(ctx context.Context, g backend.Graph, parent, nid, name string, node *backend.Properties) error {
+
+	results, err := g.CreateNodes(ctx, &backend.Nodes{nid: node})
+	if err == nil {
+		err = results.Err()
+	}
+	if err != nil {
+		return err
+	}
+
+	edgeResults, err := g.CreateEdges(ctx, &backend.Edges{parent: {nid: named(name)}})
+	if err == nil {
+		err = edgeResults.Err()
+	}
+	if err != nil {
+		// don't leave a node behind that nothing points to
+		g.DeleteNodes(ctx, &backend.Nodes{nid: &backend.Properties{}})
+		return err
+	}
+	return nil
+}
+
+// Get returns the file or folder at path.  The entries of a folder are listed in its Children.
+func (f *FS) Get(ctx context.Context, path string) (*Object, error) {
+
+	p, err := f.graph.GetPath(ctx, f.root, path)
+	if err != nil {
+		return nil, err
+	}
+	return f.object(ctx, path, p)
+}
+
+// Alter sets the given properties on the file or folder at path.  Whether it is a file or a folder can not be
+// altered and names are changed with Move.
+func (f *FS) Alter(ctx context.Context, path string, properties *backend.Properties) (*Object, error) {
+
+	p, err := f.graph.GetPath(ctx, f.root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	alter := &backend.Properties{}
+	if properties != nil {
+		alter = properties.Clone()
+	}
+	delete(*alter, TypeKey)
+	if len(*alter) > 0 {
+		results, err := f.graph.AlterNodes(ctx, &backend.Nodes{p.Last().ID: alter})
+		if err == nil {
+			err = results.Err()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f.Get(ctx, path)
+}
+
+// Move moves the file or folder at src to dst, which is the full path it will have afterwards.  The parent
+// folder of dst must exist and nothing may already be at dst.
+func (f *FS) Move(ctx context.Context, src, dst string) (*Object, error) {
+
+	from, err := f.graph.GetPath(ctx, f.root, src)
+	if err != nil {
+		return nil, err
+	}
+	if len(from.Edges) == 0 {
+		return nil, ErrRoot
+	}
+	edge := from.Edges[len(from.Edges)-1]
+
+	dir, name, err := split(dst)
+	if err != nil {
+		return nil, err
+	}
+	to, err := f.graph.GetPath(ctx, f.root, dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range to.Nodes {
+		if node.ID == edge.To {
+			return nil, ErrLoop
+		}
+	}
+	parent, err := f.object(ctx, dir, to)
+	if err != nil {
+		return nil, err
+	}
+	if !parent.IsFolder() {
+		return nil, fmt.Errorf("%s: %w", dir, ErrNotFolder)
+	}
+	if err := free(parent, dir, name); err != nil {
+		return nil, err
+	}
+
+	// the edges are changed in a single transaction when the driver supports them
+	move := func(g backend.Graph) error {
+		return relink(ctx, g, edge, parent.ID, name)
+	}
+	if t, ok := f.graph.(backend.Transactor); ok {
+		err = t.WithTx(ctx, move)
+	} else {
+		err = move(f.graph)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Get(ctx, join(dir, name))
+}
+
+// relink links the object the edge points to from parent under name instead.  Within the same parent only the
+// name of the edge changes.  Otherwise the new edge is created before the old one is deleted so a failure part
+// way, without a transaction, leaves the object reachable from both places rather than from neither.
+func relink(ctx context.Context, g backend.Graph, edge *backend.PathEdge, parent, name string) error {
+
+	if parent == edge.From {
+		results, err := g.AlterEdges(ctx, &backend.Edges{parent: {edge.To: named(name)}})
+		if err != nil {
+			return err
+		}
+		return results.Err()
+	}
+
+	moved := edge.Properties.Clone()
+	moved.SetString(NameKey, name)
+	results, err := g.CreateEdges(ctx, &backend.Edges{parent: {edge.To: moved}})
+	if err != nil {
+		return err
+	}
+	if err := results.Err(); err != nil {
+		return err
+	}
+	return unlink(ctx, g, edge.From, edge.To)
+}
+
+// Delete deletes the file or folder at path.  A folder that is not empty is only deleted along with everything
+// in it when recursive is set.
+func (f *FS) Delete(ctx context.Context, path string, recursive bool) error {
+
+	p, err := f.graph.GetPath(ctx, f.root, path)
+	if err != nil {
+		return err
+	}
+	if len(p.Edges) == 0 {
+		return ErrRoot
+	}
+	object, err := f.object(ctx, path, p)
+	if err != nil {
+		return err
+	}
+	if len(object.Children) > 0 && !recursive {
+		return fmt.Errorf("%s: %w", path, ErrNotEmpty)
+	}
+
+	edge := p.Edges[len(p.Edges)-1]
+	return f.remove(ctx, edge.From, object)
+}
+
+// remove deletes the object linked from parent along with everything in it
+func (f *FS) remove(ctx context.Context, parent string, object *Object) error {
+
+	for name, nid := range object.Children {
+		child, err := f.load(ctx, join(object.Path, name), nid, name)
+		if err != nil {
+			return err
+		}
+		if err := f.remove(ctx, object.ID, child); err != nil {
+			return err
+		}
+	}
+
+	// the edge goes first so the node is never left behind unreachable, and both go in a single transaction
+	// when the driver supports them
+	drop := func(g backend.Graph) error {
+		if err := unlink(ctx, g, parent, object.ID); err != nil {
+			return err
+		}
+		results, err := g.DeleteNodes(ctx, &backend.Nodes{object.ID: &backend.Properties{}})
+		if err != nil {
+			return err
+		}
+		return results.Err()
+	}
+	if t, ok := f.graph.(backend.Transactor); ok {
+		return t.WithTx(ctx, drop)
+	}
+	return drop(f.graph)
+}
+
+// unlink deletes the edge between parent and nid
+func unlink(ctx context.Context, g backend.Graph, parent, nid string) error {
+	results, err := g.DeleteEdges(ctx, &backend.Edges{parent: {nid: &backend.Properties{}}})
+	if err != nil {
+		return err
+	}
+	return results.Err()
+}
+
+// folder returns the folder at path or ErrNotFolder if it is a file
+func (f *FS) folder(ctx context.Context, path string) (*Object, error) {
+	object, err := f.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if !object.IsFolder() {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotFolder)
+	}
+	return object, nil
+}
+
+// free returns backend.ErrAlreadyExists if the folder already has an entry with the name
+func free(folder *Object, path, name string) error {
+	if _, ok := folder.Children[name]; ok {
+		return fmt.Errorf("%s: %w", join(path, name), backend.ErrAlreadyExists)
+	}
+	return nil
+}
+
+// object builds the object at the end of a resolved path
+func (f *FS) object(ctx context.Context, path string, p *backend.Path) (*Object, error) {
+	last := p.Last()
+	name := ""
+	if len(p.Edges) > 0 {
+		name = p.Edges[len(p.Edges)-1].Name
+	}
+	return f.build(ctx, path, last.ID, name, last.Properties)
+}
+
+// load fetches a node and builds the object for it
+func (f *FS) load(ctx context.Context, path, nid, name string) (*Object, error) {
+	nodes := &backend.Nodes{nid: &backend.Properties{}}
+	results, err := f.graph.GetNodes(ctx, nodes)
+	if err != nil {
+		return nil, err
+	}
+	if err := results.Err(); err != nil {
+		return nil, err
+	}
+	return f.build(ctx, path, nid, name, (*nodes)[nid])
+}
+
+// build makes an object out of a node, listing the entries of folders
+func (f *FS) build(ctx context.Context, path, nid, name string, properties *backend.Properties) (*Object, error) {
+	kind, err := properties.GetString(TypeKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	object := &Object{
+		ID:         nid,
+		Name:       name,
+		Path:       join(path),
+		Type:       kind,
+		Properties: properties,
+	}
+	if kind != Folder {
+		return object, nil
+	}
+
+	edges := &backend.Edges{nid: {}}
+	if _, err := f.graph.GetOutEdges(ctx, edges); err != nil {
+		return nil, err
+	}
+	object.Children = make(map[string]string, len((*edges)[nid]))
+	for tid, edge := range (*edges)[nid] {
+		child, err := edge.GetString(NameKey)
+		if err != nil {
+			continue
+		}
+		object.Children[child] = tid
+	}
+	return object, nil
+}
+
+// split breaks a path into the path of its parent folder and its name
+func split(path string) (string, string, error) {
+	components, err := backend.SplitPath(path)
+	if err != nil {
+		return "", "", err
+	}
+	if len(components) == 0 {
+		return "", "", ErrRoot
+	}
+	return join(components[:len(components)-1]...), components[len(components)-1], nil
+}
+
+// join builds a clean absolute path out of components
+func join(components ...string) string {
+	parts := make([]string, 0, len(components))
+	for _, component := range components {
+		for _, part := range strings.Split(component, "/") {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// named returns edge properties with the given name
+func named(name string) *backend.Properties {
+	properties := &backend.Properties{}
+	properties.SetString(NameKey, name)
+	return properties
+}
+
+// newID returns a random nid
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
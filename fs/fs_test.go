@@ -0,0 +1,223 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/sir-wiggles/bcfs/backend"
+	_ "github.com/sir-wiggles/bcfs/drivers/mem"
+)
+
+// newFS returns an initialized filesystem on a fresh source of the in-memory driver
+func newFS(t *testing.T) *FS {
+	graph, err := backend.GetBackend(&backend.Config{"name": "mem", "sid": fmt.Sprintf("fs-%d", rand.Int63())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := New(graph, "root")
+	if err := f.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func Test_Create(t *testing.T) {
+	ctx := context.Background()
+	f := newFS(t)
+
+	// + test
+	if _, err := f.CreateFolder(ctx, "/a", nil); err != nil {
+		t.Fatal(err)
+	}
+	properties := &backend.Properties{}
+	properties.SetString("owner", "me")
+	file, err := f.CreateFile(ctx, "/a/b.txt", properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Type != File || file.Name != "b.txt" || file.Path != "/a/b.txt" {
+		t.Errorf("unexpected file %+v", file)
+	}
+	if owner, _ := file.Properties.GetString("owner"); owner != "me" {
+		t.Errorf("expected owner me got %s", owner)
+	}
+
+	folder, err := f.Get(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !folder.IsFolder() || folder.Children["b.txt"] != file.ID {
+		t.Errorf("expected folder with b.txt got %+v", folder)
+	}
+
+	// - test
+	if _, err := f.CreateFile(ctx, "/a/b.txt", nil); !errors.Is(err, backend.ErrAlreadyExists) {
+		t.Errorf("expected ErrAlreadyExists got %v", err)
+	}
+	if _, err := f.CreateFile(ctx, "/a/b.txt/c", nil); !errors.Is(err, ErrNotFolder) {
+		t.Errorf("expected ErrNotFolder got %v", err)
+	}
+	if _, err := f.CreateFile(ctx, "/missing/c", nil); !errors.Is(err, backend.ErrNotFound) {
+		t.Errorf("expected ErrNotFound got %v", err)
+	}
+}
+
+func Test_Alter(t *testing.T) {
+	ctx := context.Background()
+	f := newFS(t)
+	if _, err := f.CreateFile(ctx, "/b.txt", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	properties := &backend.Properties{}
+	properties.SetString("owner", "you")
+	properties.SetString(TypeKey, Folder)
+	file, err := f.Alter(ctx, "/b.txt", properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner, _ := file.Properties.GetString("owner"); owner != "you" {
+		t.Errorf("expected owner you got %s", owner)
+	}
+	if file.Type != File {
+		t.Errorf("the type should not be altered, got %s", file.Type)
+	}
+}
+
+func Test_Move(t *testing.T) {
+	ctx := context.Background()
+	f := newFS(t)
+	for _, path := range []string{"/a", "/a/b", "/c"} {
+		if _, err := f.CreateFolder(ctx, path, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file, err := f.CreateFile(ctx, "/a/b/d.txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// + test
+	moved, err := f.Move(ctx, "/a/b", "/c/e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Name != "e" || moved.Children["d.txt"] != file.ID {
+		t.Errorf("unexpected folder %+v", moved)
+	}
+	if _, err := f.Get(ctx, "/a/b"); !errors.Is(err, backend.ErrNotFound) {
+		t.Errorf("expected the old path to be gone got %v", err)
+	}
+	if _, err := f.Get(ctx, "/c/e/d.txt"); err != nil {
+		t.Error(err)
+	}
+
+	// renaming within the same folder keeps the object
+	renamed, err := f.Move(ctx, "/c/e/d.txt", "/c/e/f.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.ID != file.ID {
+		t.Errorf("expected %s got %s", file.ID, renamed.ID)
+	}
+	if _, err := f.Get(ctx, "/c/e/d.txt"); !errors.Is(err, backend.ErrNotFound) {
+		t.Errorf("expected the old name to be gone got %v", err)
+	}
+
+	// - test
+	if _, err := f.Move(ctx, "/c", "/c/e/c"); !errors.Is(err, ErrLoop) {
+		t.Errorf("expected ErrLoop got %v", err)
+	}
+	if _, err := f.Move(ctx, "/a", "/c/e"); !errors.Is(err, backend.ErrAlreadyExists) {
+		t.Errorf("expected ErrAlreadyExists got %v", err)
+	}
+	if _, err := f.Move(ctx, "/", "/x"); !errors.Is(err, ErrRoot) {
+		t.Errorf("expected ErrRoot got %v", err)
+	}
+}
+
+// failingDeletes is a graph whose DeleteEdges always fails, including in a transaction
+type failingDeletes struct {
+	backend.Graph
+}
+
+var errDelete = errors.New("delete failed")
+
+func (g failingDeletes) DeleteEdges(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error) {
+	return nil, errDelete
+}
+
+func (g failingDeletes) WithTx(ctx context.Context, fn func(backend.Graph) error) error {
+	return g.Graph.(backend.Transactor).WithTx(ctx, func(tx backend.Graph) error {
+		return fn(failingDeletes{tx})
+	})
+}
+
+func Test_MoveRollback(t *testing.T) {
+	ctx := context.Background()
+	f := newFS(t)
+	for _, path := range []string{"/a", "/c"} {
+		if _, err := f.CreateFolder(ctx, path, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := f.CreateFile(ctx, "/a/b.txt", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the new edge is rolled back along with the failed delete of the old one
+	f = New(failingDeletes{f.graph}, f.root)
+	if _, err := f.Move(ctx, "/a/b.txt", "/c/b.txt"); !errors.Is(err, errDelete) {
+		t.Fatalf("expected the delete to fail got %v", err)
+	}
+	if _, err := f.Get(ctx, "/a/b.txt"); err != nil {
+		t.Errorf("expected the file to stay put got %v", err)
+	}
+	if _, err := f.Get(ctx, "/c/b.txt"); !errors.Is(err, backend.ErrNotFound) {
+		t.Errorf("expected the move to be rolled back got %v", err)
+	}
+}
+
+func Test_Delete(t *testing.T) {
+	ctx := context.Background()
+	f := newFS(t)
+	for _, path := range []string{"/a", "/a/b"} {
+		if _, err := f.CreateFolder(ctx, path, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file, err := f.CreateFile(ctx, "/a/b/c.txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// - test
+	if err := f.Delete(ctx, "/a", false); !errors.Is(err, ErrNotEmpty) {
+		t.Errorf("expected ErrNotEmpty got %v", err)
+	}
+	if err := f.Delete(ctx, "/", true); !errors.Is(err, ErrRoot) {
+		t.Errorf("expected ErrRoot got %v", err)
+	}
+
+	// + test
+	if err := f.Delete(ctx, "/a", true); err != nil {
+		t.Fatal(err)
+	}
+	root, err := f.Get(ctx, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Children) != 0 {
+		t.Errorf("expected an empty root got %v", root.Children)
+	}
+	results, err := f.graph.GetNodes(ctx, &backend.Nodes{file.ID: &backend.Properties{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(results[file.ID], backend.ErrNotFound) {
+		t.Errorf("expected the file node to be deleted got %v", results[file.ID])
+	}
+}
@@ -10,13 +10,26 @@ function will be added to the registry map in backend.backend.
 
 Once everything is setup, calling GetBackend and passing in backend.Config will initialize the connections to the DB
 returning a graph that you can call the interface methods on.
+
+Usage:
+
+	bcfs -c config.ini            serve the filesystem over http
+	bcfs -c config.ini provision  create the tables, indexes and constraints the backend needs and exit
 */
 
 import (
+	"context"
 	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/sir-wiggles/bcfs/backend"
+	"github.com/sir-wiggles/bcfs/server"
 	// Load all the knows drivers.  These drivers get registered in their init method call.
+	_ "github.com/sir-wiggles/bcfs/drivers/ddb"
+	_ "github.com/sir-wiggles/bcfs/drivers/mem"
 	_ "github.com/sir-wiggles/bcfs/drivers/neo"
 
 	log "github.com/Sirupsen/logrus"
@@ -26,6 +39,7 @@ import (
 // A generic config that holds configuration options for the backend
 type FilesystemConfig struct {
 	BackendConfig *backend.Config
+	ServerConfig  server.Config
 	LogLevel      log.Level
 }
 
@@ -57,21 +71,71 @@ func handleConfig(configFilename *string) (*FilesystemConfig, error) {
 	var backendConfig *backend.Config
 	switch backendName {
 	case "neo":
+		// bolt listens on its own port
+		transport := cfg.StringFromSection(backendName, "transport", "rest")
+		port := int64(7474)
+		if transport == "bolt" {
+			port = 7687
+		}
+		backendConfig = &backend.Config{
+			"name":                    "neo",
+			"transport":               transport,
+			"user":                    cfg.StringFromSection(backendName, "user", ""),
+			"password":                cfg.StringFromSection(backendName, "password", ""),
+			"host":                    cfg.StringFromSection(backendName, "host", ""),
+			"port":                    cfg.IntegerFromSection(backendName, "port", port),
+			"sid":                     cfg.StringFromSection(backendName, "sid", "default"),
+			"batch-size":              cfg.IntegerFromSection(backendName, "batch-size", 1000),
+			"ensure-schema":           cfg.BooleanFromSection(backendName, "ensure-schema", true),
+			"database":                cfg.StringFromSection(backendName, "database", ""),
+			"routing":                 cfg.BooleanFromSection(backendName, "routing", false),
+			"max-pool-size":           cfg.IntegerFromSection(backendName, "max-pool-size", 100),
+			"connect-timeout":         cfg.IntegerFromSection(backendName, "connect-timeout", 5),
+			"acquire-timeout":         cfg.IntegerFromSection(backendName, "acquire-timeout", 60),
+			"max-connection-lifetime": cfg.IntegerFromSection(backendName, "max-connection-lifetime", 3600),
+			"tls":                     cfg.BooleanFromSection(backendName, "tls", false),
+			"tls-ca-file":             cfg.StringFromSection(backendName, "tls-ca-file", ""),
+			"tls-skip-verify":         cfg.BooleanFromSection(backendName, "tls-skip-verify", false),
+		}
+	case "mem":
 		backendConfig = &backend.Config{
-			"name":     "neo",
-			"user":     cfg.StringFromSection(backendName, "user", ""),
-			"password": cfg.StringFromSection(backendName, "password", ""),
-			"host":     cfg.StringFromSection(backendName, "host", ""),
-			"port":     cfg.IntegerFromSection(backendName, "port", 7474),
+			"name": "mem",
+			"sid":  cfg.StringFromSection(backendName, "sid", "default"),
 		}
 	case "ddb":
 		backendConfig = &backend.Config{
-		// where ddb config options would go
+			"name":             "ddb",
+			"key":              cfg.StringFromSection(backendName, "key", ""),
+			"secret":           cfg.StringFromSection(backendName, "secret", ""),
+			"session-token":    cfg.StringFromSection(backendName, "session-token", ""),
+			"region":           cfg.StringFromSection(backendName, "region", ""),
+			"endpoint":         cfg.StringFromSection(backendName, "endpoint", ""),
+			"timeout":          cfg.IntegerFromSection(backendName, "timeout", 10),
+			"max-retries":      cfg.IntegerFromSection(backendName, "max-retries", 3),
+			"retry-attempts":   cfg.IntegerFromSection(backendName, "retry-attempts", 10),
+			"retry-base-delay": cfg.IntegerFromSection(backendName, "retry-base-delay", 25),
+			"retry-max-delay":  cfg.IntegerFromSection(backendName, "retry-max-delay", 5000),
+			"concurrency":      cfg.IntegerFromSection(backendName, "concurrency", 8),
+			"node-table":       cfg.StringFromSection(backendName, "node-table", "fs-node"),
+			"edge-table":       cfg.StringFromSection(backendName, "edge-table", "fs-edge"),
+			"read-capacity":    cfg.IntegerFromSection(backendName, "read-capacity", 0),
+			"write-capacity":   cfg.IntegerFromSection(backendName, "write-capacity", 0),
+			"sid":              cfg.StringFromSection(backendName, "sid", "default"),
 		}
 	}
 
+	// Get the http server options, all the timeouts are given in seconds
+	serverConfig := server.Config{
+		Listen:          cfg.StringFromSection("http", "listen", ":8080"),
+		ReadTimeout:     seconds(cfg.IntegerFromSection("http", "read-timeout", 30)),
+		WriteTimeout:    seconds(cfg.IntegerFromSection("http", "write-timeout", 30)),
+		IdleTimeout:     seconds(cfg.IntegerFromSection("http", "idle-timeout", 120)),
+		ShutdownTimeout: seconds(cfg.IntegerFromSection("http", "shutdown-timeout", 30)),
+	}
+
 	fcfg := &FilesystemConfig{
 		BackendConfig: backendConfig,
+		ServerConfig:  serverConfig,
 		LogLevel:      logLevel,
 	}
 
@@ -89,8 +153,79 @@ func main() {
 
 	log.SetLevel(cfg.LogLevel)
 
-	_, err = backend.GetBackend(cfg.BackendConfig)
+	graph, err := backend.GetBackend(cfg.BackendConfig)
 	if err != nil {
 		log.Fatalf(err.Error())
 	}
+
+	switch flag.Arg(0) {
+	case "":
+	case "provision":
+		provision(graph)
+		return
+	default:
+		log.Fatalf("Unknown command %s", flag.Arg(0))
+	}
+
+	srv := server.New(graph, cfg.ServerConfig)
+
+	// Stop taking new requests on SIGTERM or SIGINT and give the ones in flight a chance to finish
+	done := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+		sig := <-signals
+		log.Infof("Received %s, shutting down", sig)
+		if err := srv.Shutdown(context.Background()); err != nil {
+			log.Errorf("Failed to shut down cleanly: %s", err.Error())
+		}
+		close(done)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil {
+		log.Fatalf(err.Error())
+	}
+	<-done
+
+	if reporter, ok := graph.(backend.CapacityReporter); ok {
+		for _, usage := range reporter.ConsumedCapacity() {
+			log.Infof("Consumed capacity of %s %s: %d requests, %.1f read units, %.1f write units",
+				usage.SourceID, usage.Op, usage.Requests, usage.ReadUnits, usage.WriteUnits)
+		}
+	}
+	if closer, ok := graph.(backend.Closer); ok {
+		if err := closer.Close(context.Background()); err != nil {
+			log.Errorf("Failed to close the backend: %s", err.Error())
+		}
+	}
+}
+
+// provision sets up what the backend needs before it can be used and logs what was created.  It is safe to run
+// against a backend that is already set up.
+func provision(graph backend.Graph) {
+	p, ok := graph.(backend.Provisioner)
+	if !ok {
+		log.Infof("The backend has nothing to provision")
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	report, err := p.Provision(ctx)
+	if report != nil {
+		for _, name := range report.Existing {
+			log.Infof("Exists: %s", name)
+		}
+		for _, name := range report.Created {
+			log.Infof("Created: %s", name)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Failed to provision the backend: %s", err.Error())
+	}
+}
+
+// seconds turns a number of seconds from the config into a duration
+func seconds(n int64) time.Duration {
+	return time.Duration(n) * time.Second
 }
@@ -0,0 +1,273 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// errBadRequest marks errors caused by the request rather than the backend
+var errBadRequest = errors.New("bad request")
+
+// nodesResponse is the body returned by the node endpoints
+type nodesResponse struct {
+	Nodes  *backend.Nodes    `json:"nodes,omitempty"`
+	Errors map[string]string `json:"errors"`
+}
+
+// edgesResponse is the body returned by the edge endpoints
+type edgesResponse struct {
+	Edges  *backend.Edges               `json:"edges,omitempty"`
+	Errors map[string]map[string]string `json:"errors"`
+}
+
+// handleNodes serves GET /nodes?id=nid... for reads and POST, PATCH and DELETE /nodes with a body of nodes to
+// create, alter or delete.
+func (s *Server) handleNodes(w http.ResponseWriter, r *http.Request) {
+
+	var (
+		nodes   = &backend.Nodes{}
+		results backend.NodeResults
+		err     error
+	)
+
+	if r.Method == http.MethodGet {
+		for _, nid := range r.URL.Query()["id"] {
+			(*nodes)[nid] = &backend.Properties{}
+		}
+		if len(*nodes) == 0 {
+			writeError(w, fmt.Errorf("%w: at least one id is required", errBadRequest))
+			return
+		}
+		results, err = s.graph.GetNodes(r.Context(), nodes)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		for _, nid := range results.Failed() {
+			delete(*nodes, nid)
+		}
+		writeJSON(w, http.StatusOK, nodesResponse{Nodes: nodes, Errors: nodeErrors(results)})
+		return
+	}
+
+	var write func(context.Context, *backend.Nodes) (backend.NodeResults, error)
+	switch r.Method {
+	case http.MethodPost:
+		write = s.graph.CreateNodes
+	case http.MethodPatch:
+		write = s.graph.AlterNodes
+	case http.MethodDelete:
+		write = s.graph.DeleteNodes
+	default:
+		w.Header().Set("Allow", "GET, POST, PATCH, DELETE")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if err := decode(w, r, nodes); err != nil {
+		writeError(w, err)
+		return
+	}
+	results, err = write(r.Context(), nodes)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nodesResponse{Errors: nodeErrors(results)})
+}
+
+// handleEdges serves POST, PATCH and DELETE /edges with a body of edges to create, alter or delete
+func (s *Server) handleEdges(w http.ResponseWriter, r *http.Request) {
+
+	var (
+		edges   = &backend.Edges{}
+		results backend.EdgeResults
+		err     error
+	)
+
+	var write func(context.Context, *backend.Edges) (backend.EdgeResults, error)
+	switch r.Method {
+	case http.MethodPost:
+		write = s.graph.CreateEdges
+	case http.MethodPatch:
+		write = s.graph.AlterEdges
+	case http.MethodDelete:
+		write = s.graph.DeleteEdges
+	default:
+		w.Header().Set("Allow", "POST, PATCH, DELETE")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if err := decode(w, r, edges); err != nil {
+		writeError(w, err)
+		return
+	}
+	results, err = write(r.Context(), edges)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, edgesResponse{Errors: edgeErrors(results)})
+}
+
+// handleOutEdges serves GET /edges/out?from=nid...[&to=nid...].  Without any to every edge going out of the from
+// nodes is returned, otherwise only the edges to the given nodes.
+func (s *Server) handleOutEdges(w http.ResponseWriter, r *http.Request) {
+	s.getEdges(w, r, "from", "to", s.graph.GetOutEdges)
+}
+
+// handleInEdges serves GET /edges/in?to=nid...[&from=nid...].  The edges in the response are keyed by the to
+// nid first, the same way GetInEdges keys them.
+func (s *Server) handleInEdges(w http.ResponseWriter, r *http.Request) {
+	s.getEdges(w, r, "to", "from", s.graph.GetInEdges)
+}
+
+func (s *Server) getEdges(w http.ResponseWriter, r *http.Request, outer, inner string, get func(ctx context.Context, edges *backend.Edges) (backend.EdgeResults, error)) {
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	query := r.URL.Query()
+	if len(query[outer]) == 0 {
+		writeError(w, fmt.Errorf("%w: at least one %s is required", errBadRequest, outer))
+		return
+	}
+	edges := &backend.Edges{}
+	for _, a := range query[outer] {
+		(*edges)[a] = make(map[string]*backend.Properties)
+		for _, b := range query[inner] {
+			(*edges)[a][b] = &backend.Properties{}
+		}
+	}
+
+	results, err := get(r.Context(), edges)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	for _, pair := range results.Failed() {
+		delete((*edges)[pair[0]], pair[1])
+	}
+	writeJSON(w, http.StatusOK, edgesResponse{Edges: edges, Errors: edgeErrors(results)})
+}
+
+// handlePath serves GET /path?root=nid&path=/a/b
+func (s *Server) handlePath(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	root := r.URL.Query().Get("root")
+	if root == "" {
+		writeError(w, fmt.Errorf("%w: root is required", errBadRequest))
+		return
+	}
+	p, err := s.graph.GetPath(r.Context(), root, r.URL.Query().Get("path"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// decode reads the JSON body of the request into v.  A null in place of the properties of a node or an edge is
+// refused since the drivers have nothing to write for it.  A null property removes the property in a PATCH, the
+// same as a nil property does in AlterNodes and AlterEdges, and is refused by every other method, as is a null
+// inside a list or a map.
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	body := http.MaxBytesReader(w, r.Body, MaxBodySize)
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s", errBadRequest, err.Error())
+	}
+
+	remove := r.Method == http.MethodPatch
+	var err error
+	switch v := v.(type) {
+	case *backend.Nodes:
+		for nid, properties := range *v {
+			if err = checkProperties(properties, remove); err != nil {
+				err = fmt.Errorf("node %s: %w", nid, err)
+				break
+			}
+		}
+	case *backend.Edges:
+	outer:
+		for fid, tos := range *v {
+			for tid, properties := range tos {
+				if err = checkProperties(properties, remove); err != nil {
+					err = fmt.Errorf("edge %s %s: %w", fid, tid, err)
+					break outer
+				}
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", errBadRequest, err.Error())
+	}
+	return nil
+}
+
+// checkProperties returns an error if the properties, or any property in them or in their lists and maps, is
+// null.  remove lets the properties themselves be null to have them removed.
+func checkProperties(properties *backend.Properties, remove bool) error {
+	if properties == nil {
+		return errors.New("properties can not be null")
+	}
+	for key, property := range *properties {
+		if property == nil && remove {
+			continue
+		}
+		if err := checkProperty(property); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func checkProperty(property *backend.Property) error {
+	if property == nil {
+		return errors.New("property can not be null")
+	}
+	switch value := property.Value.(type) {
+	case []*backend.Property:
+		for i, element := range value {
+			if err := checkProperty(element); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+	case *backend.Properties:
+		return checkProperties(value, false)
+	}
+	return nil
+}
+
+// nodeErrors lists the error of every nid the operation failed for
+func nodeErrors(results backend.NodeResults) map[string]string {
+	errs := make(map[string]string)
+	for _, nid := range results.Failed() {
+		errs[nid] = results[nid].Error()
+	}
+	return errs
+}
+
+// edgeErrors lists the error of every edge the operation failed for
+func edgeErrors(results backend.EdgeResults) map[string]map[string]string {
+	errs := make(map[string]map[string]string)
+	for _, pair := range results.Failed() {
+		if errs[pair[0]] == nil {
+			errs[pair[0]] = make(map[string]string)
+		}
+		errs[pair[0]][pair[1]] = results[pair[0]][pair[1]].Error()
+	}
+	return errs
+}
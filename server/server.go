@@ -0,0 +1,140 @@
+// Package server exposes a backend.Graph over HTTP with JSON bodies so bcfs can run as a microservice.
+//
+// Node and edge bodies are the JSON encoding of backend.Nodes and backend.Edges, e.g.
+//
+//	{"nid": {"name": {"type": "string", "value": "a.txt"}}}
+//
+// Reads take the nids in the query string while writes take the nodes or edges in the body.  Batches that only
+// partly succeed still return 200 with the items that failed listed under "errors".
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sir-wiggles/bcfs/backend"
+)
+
+// MaxBodySize is the largest request body the server will read
+const MaxBodySize = 10 << 20
+
+// Config holds the options of the HTTP server
+type Config struct {
+	// Listen is the address to listen on, e.g. ":8080"
+	Listen string
+	// ReadTimeout, WriteTimeout and IdleTimeout are passed on to the http.Server
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownTimeout is how long in flight requests get to finish once the server is asked to stop
+	ShutdownTimeout time.Duration
+}
+
+// Server serves a graph over HTTP
+type Server struct {
+	graph  backend.Graph
+	config Config
+	http   *http.Server
+}
+
+// New returns a server for the graph
+func New(graph backend.Graph, config Config) *Server {
+	s := &Server{graph: graph, config: config}
+	s.http = &http.Server{
+		Addr:         config.Listen,
+		Handler:      s.Handler(),
+		ReadTimeout:  config.ReadTimeout,
+		WriteTimeout: config.WriteTimeout,
+		IdleTimeout:  config.IdleTimeout,
+	}
+	return s
+}
+
+// Handler returns the handler with all the routes of the server
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/nodes", s.handleNodes)
+	mux.HandleFunc("/edges", s.handleEdges)
+	mux.HandleFunc("/edges/in", s.handleInEdges)
+	mux.HandleFunc("/edges/out", s.handleOutEdges)
+	mux.HandleFunc("/path", s.handlePath)
+	return logRequests(mux)
+}
+
+// ListenAndServe serves until Shutdown is called.  Unlike http.Server it returns nil once shut down.
+func (s *Server) ListenAndServe() error {
+	log.Infof("Listening on %s", s.config.Listen)
+	if err := s.http.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting connections and waits up to the ShutdownTimeout for in flight requests to finish
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.config.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.ShutdownTimeout)
+		defer cancel()
+	}
+	return s.http.Shutdown(ctx)
+}
+
+// logRequests logs every request at debug level
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		log.Debugf("%s %s %s", r.Method, r.URL.RequestURI(), time.Since(start))
+	})
+}
+
+// writeJSON writes v as the JSON body of the response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("Failed to write response: %s", err.Error())
+	}
+}
+
+// StatusClientClosedRequest is the status of a request the client went away from before it was answered, as
+// nginx logs it.  The client never sees it.
+const StatusClientClosedRequest = 499
+
+// writeError writes the error with the status matching its kind.  Errors of the server are logged, those of the
+// request and of a client that went away are not.
+func writeError(w http.ResponseWriter, err error) {
+	code := status(err)
+	if code >= http.StatusInternalServerError {
+		log.Errorf("Request failed: %s", err.Error())
+	}
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// status maps an error onto an HTTP status code
+func status(err error) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest
+	case errors.Is(err, errBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, backend.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, backend.ErrAlreadyExists), errors.Is(err, backend.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, backend.ErrUnsupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, backend.ErrThrottled):
+		return http.StatusTooManyRequests
+	case errors.Is(err, backend.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sir-wiggles/bcfs/backend"
+	_ "github.com/sir-wiggles/bcfs/drivers/mem"
+)
+
+// newServer returns a test server for a fresh source of the in-memory driver
+func newServer(t *testing.T) *httptest.Server {
+	graph, err := backend.GetBackend(&backend.Config{"name": "mem", "sid": fmt.Sprintf("server-%d", rand.Int63())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(New(graph, Config{}).Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// do sends a request with body encoded as JSON and decodes the response into out
+func do(t *testing.T, method, url string, body, out interface{}) int {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func named(name string) *backend.Properties {
+	properties := &backend.Properties{}
+	properties.SetString("name", name)
+	return properties
+}
+
+func Test_Nodes(t *testing.T) {
+	ts := newServer(t)
+
+	// + test
+	var created nodesResponse
+	if code := do(t, "POST", ts.URL+"/nodes", backend.Nodes{"a": named("a"), "b": named("b")}, &created); code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", code)
+	}
+	if len(created.Errors) != 0 {
+		t.Errorf("expected no errors got %v", created.Errors)
+	}
+
+	var got nodesResponse
+	do(t, "GET", ts.URL+"/nodes?id=a&id=missing", nil, &got)
+	if name, _ := (*got.Nodes)["a"].GetString("name"); name != "a" {
+		t.Errorf("expected a got %s", name)
+	}
+	if _, ok := got.Errors["missing"]; !ok {
+		t.Errorf("expected an error for missing got %v", got.Errors)
+	}
+
+	do(t, "PATCH", ts.URL+"/nodes", backend.Nodes{"a": named("c")}, nil)
+	do(t, "DELETE", ts.URL+"/nodes", backend.Nodes{"b": &backend.Properties{}}, nil)
+	got = nodesResponse{}
+	do(t, "GET", ts.URL+"/nodes?id=a&id=b", nil, &got)
+	if name, _ := (*got.Nodes)["a"].GetString("name"); name != "c" {
+		t.Errorf("expected c got %s", name)
+	}
+	if _, ok := got.Errors["b"]; !ok {
+		t.Errorf("expected b to be deleted got %v", got)
+	}
+
+	// - test
+	if code := do(t, "GET", ts.URL+"/nodes", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 got %d", code)
+	}
+	if code := do(t, "POST", ts.URL+"/nodes", "not nodes", nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 got %d", code)
+	}
+	// - test an unsupported method is refused before its body is read
+	req, _ := http.NewRequest("PUT", ts.URL+"/nodes", bytes.NewBufferString("not json"))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 got %d", resp.StatusCode)
+	}
+	for _, body := range []string{`{"a": null}`, `{"a": {"name": null}}`} {
+		req, _ := http.NewRequest("POST", ts.URL+"/nodes", bytes.NewBufferString(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400 got %d", body, resp.StatusCode)
+		}
+	}
+	if code := do(t, "PUT", ts.URL+"/nodes", backend.Nodes{}, nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 got %d", code)
+	}
+	for _, body := range []string{`{"a": null}`, `{"a": {"tags": {"type": "list", "value": [null]}}}`} {
+		req, _ := http.NewRequest("PATCH", ts.URL+"/nodes", bytes.NewBufferString(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("PATCH %s: expected 400 got %d", body, resp.StatusCode)
+		}
+	}
+
+	// + test a null property in a PATCH removes it
+	req, _ = http.NewRequest("PATCH", ts.URL+"/nodes", bytes.NewBufferString(`{"a": {"name": null}}`))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 got %d", resp.StatusCode)
+	}
+	got = nodesResponse{}
+	do(t, "GET", ts.URL+"/nodes?id=a", nil, &got)
+	if _, ok := (*(*got.Nodes)["a"])["name"]; ok {
+		t.Errorf("expected the name of a to be removed got %v", (*got.Nodes)["a"])
+	}
+}
+
+func Test_status(t *testing.T) {
+	// + test
+	if code := status(fmt.Errorf("get nodes: %w", context.Canceled)); code != StatusClientClosedRequest {
+		t.Errorf("expected a cancelled request to be %d got %d", StatusClientClosedRequest, code)
+	}
+	if code := status(backend.NewError("mem", "GetNodes", backend.ErrThrottled, errors.New("slow down"))); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 got %d", code)
+	}
+
+	// - test
+	if code := status(errors.New("other")); code != http.StatusInternalServerError {
+		t.Errorf("expected 500 got %d", code)
+	}
+}
+
+func Test_EdgesAndPath(t *testing.T) {
+	ts := newServer(t)
+	do(t, "POST", ts.URL+"/nodes", backend.Nodes{"root": named("root"), "a": named("a"), "b": named("b")}, nil)
+	do(t, "POST", ts.URL+"/edges", backend.Edges{"root": {"a": named("a")}, "a": {"b": named("b")}}, nil)
+
+	// + test
+	var out edgesResponse
+	do(t, "GET", ts.URL+"/edges/out?from=root", nil, &out)
+	if _, ok := (*out.Edges)["root"]["a"]; !ok {
+		t.Errorf("expected root -> a got %v", out.Edges)
+	}
+
+	var in edgesResponse
+	do(t, "GET", ts.URL+"/edges/in?to=b&from=a", nil, &in)
+	if name, _ := (*in.Edges)["b"]["a"].GetString("name"); name != "b" {
+		t.Errorf("expected b got %s", name)
+	}
+
+	var p backend.Path
+	if code := do(t, "GET", ts.URL+"/path?root=root&path=/a/b", nil, &p); code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", code)
+	}
+	if p.Last().ID != "b" {
+		t.Errorf("expected b got %s", p.Last().ID)
+	}
+
+	// - test
+	if code := do(t, "GET", ts.URL+"/path?root=root&path=/a/missing", nil, nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 got %d", code)
+	}
+	if code := do(t, "GET", ts.URL+"/edges/out", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 got %d", code)
+	}
+}